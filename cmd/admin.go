@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"hyperlocal/internal/db/postgres"
+	"hyperlocal/internal/entities/enums"
+	"hyperlocal/internal/models"
+)
+
+const adminUsage = "usage: hyperlocal admin grant <username> [user|moderator|admin] | admin reconcile-votes"
+
+// runAdmin implements the `hyperlocal admin` maintenance commands
+func runAdmin(args []string) {
+	if len(args) == 0 {
+		log.Fatalln(adminUsage)
+	}
+
+	switch args[0] {
+	case "grant":
+		runGrant(args[1:])
+	case "reconcile-votes":
+		runReconcileVotes()
+	default:
+		log.Fatalln(adminUsage)
+	}
+}
+
+// runGrant implements `hyperlocal admin grant <username> [role]`, used to
+// bootstrap the first admin before anyone can reach the role endpoints
+func runGrant(args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		log.Fatalln(adminUsage)
+	}
+
+	role := enums.RoleAdmin
+	if len(args) == 2 {
+		role = enums.Role(args[1])
+	}
+	if !role.IsValid() {
+		log.Fatalln("Invalid role", role)
+	}
+
+	db := postgres.Connect()
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	if err := postgres.EnsureMigrated(db); err != nil {
+		log.Fatalln(err)
+	}
+
+	model := models.New(db)
+
+	user, err := model.GetUserByUsername(args[0])
+	if err != nil {
+		log.Fatalln("Failed to find user", args[0], err)
+	}
+
+	if err := model.SetUserRole(user.ID, role); err != nil {
+		log.Fatalln("Failed to set role", err)
+	}
+
+	fmt.Printf("User %s now has the %s role\n", args[0], role)
+}
+
+// runReconcileVotes implements `hyperlocal admin reconcile-votes`, a one-off
+// run of the job the server otherwise runs every VOTE_RECONCILE_INTERVAL
+func runReconcileVotes() {
+	db := postgres.Connect()
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	if err := postgres.EnsureMigrated(db); err != nil {
+		log.Fatalln(err)
+	}
+
+	model := models.New(db)
+
+	fixed, err := model.ReconcileVoteCounts()
+	if err != nil {
+		log.Fatalln("Failed to reconcile vote counts", err)
+	}
+
+	fmt.Printf("Corrected vote counts on %d posts and comments\n", fixed)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"hyperlocal/internal/db/postgres"
+)
+
+// runMigrate implements `hyperlocal migrate up|down|status`
+func runMigrate(args []string) {
+	if len(args) != 1 {
+		log.Fatalln("usage: hyperlocal migrate up|down|status")
+	}
+
+	db := postgres.Connect()
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	switch args[0] {
+	case "up":
+		if err := postgres.MigrateUp(db); err != nil {
+			log.Fatalln("Migration failed", err)
+		}
+		fmt.Println("Database is up to date")
+	case "down":
+		if err := postgres.MigrateDown(db); err != nil {
+			log.Fatalln("Rollback failed", err)
+		}
+	case "status":
+		status, err := postgres.MigrateStatus(db)
+		if err != nil {
+			log.Fatalln("Failed to read migration status", err)
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalln("usage: hyperlocal migrate up|down|status")
+	}
+}
@@ -1,22 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	_ "hyperlocal/docs"
 	"hyperlocal/internal/db/postgres"
 	"hyperlocal/internal/handlers"
+	"hyperlocal/internal/jobs"
+	"hyperlocal/internal/keys"
 	"hyperlocal/internal/models"
 	"hyperlocal/internal/services"
+	"hyperlocal/internal/storage"
 	"hyperlocal/internal/web/rest"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
-	"github.com/rs/cors"
-	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
 func main() {
@@ -27,45 +33,121 @@ func main() {
 		}
 	}
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "admin":
+			runAdmin(os.Args[2:])
+			return
+		case "serve":
+		default:
+			log.Fatalln("usage: hyperlocal [serve | migrate up|down|status | admin grant <username> [role] | admin reconcile-votes]")
+		}
+	}
+
 	v := validator.New()
 
 	db := postgres.Connect()
 
+	if err := postgres.EnsureMigrated(db); err != nil {
+		log.Fatalln("Refusing to start:", err)
+	}
+
 	model := models.New(db)
 	fmt.Println("Model layer initialized")
 
-	service := services.New(model)
+	keyRing, err := keys.LoadFromEnv()
+	if err != nil {
+		log.Fatalln("Failed to load JWT signing keys", err)
+	}
+
+	serviceConfig, err := services.ConfigFromEnv()
+	if err != nil {
+		log.Fatalln("Invalid service configuration", err)
+	}
+
+	blobs, err := storage.LocalStoreFromEnv()
+	if err != nil {
+		log.Fatalln("Failed to open media store", err)
+	}
+
+	service := services.New(model, keyRing, blobs, serviceConfig)
 	fmt.Println("Service layer initialized")
 
 	handler := handlers.New(service, v)
 	fmt.Println("Handler layer initialized")
 
-	r := rest.NewRouter(handler)
+	cfg, err := rest.ConfigFromEnv()
+	if err != nil {
+		log.Fatalln("Invalid server configuration", err)
+	}
+	// Serve the media ourselves unless MEDIA_BASE_URL points at another host
+	if strings.HasPrefix(blobs.BaseURL(), "/") {
+		cfg.MediaPrefix, cfg.Media = blobs.BaseURL(), blobs
+	}
+	srv := rest.NewServer(cfg, handler, service)
 	fmt.Println("Routers loaded")
-
-	r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"),
-		httpSwagger.DeepLinking(true),
-		httpSwagger.DocExpansion("none"),
-		httpSwagger.DomID("swagger-ui"),
-	))
 	fmt.Println("Swagger documentation available at /swagger/index.html")
 
-	allowedOrigins := []string{"http://localhost:3000"}
-	if os.Getenv("ENV") == "prod" {
-		allowedOrigins = []string{"https://hyperlocal-frontend.vercel.app"}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Bring posts made under a finer MIN_LOCATION_PRECISION up to the current one
+	go func() {
+		coarsened, err := service.CoarsenPostLocations()
+		if err != nil {
+			log.Println("Failed to coarsen post locations", err)
+		}
+		if coarsened > 0 {
+			log.Printf("Coarsened the location of %d posts to %s precision", coarsened, serviceConfig.MinLocationPrecision)
+		}
+	}()
+
+	if serviceConfig.VoteReconcileInterval > 0 {
+		go jobs.Every(ctx, "vote reconciliation", serviceConfig.VoteReconcileInterval, func(ctx context.Context) error {
+			fixed, err := service.ReconcileVoteCounts()
+			if fixed > 0 {
+				log.Printf("Corrected vote counts on %d posts and comments", fixed)
+			}
+			return err
+		})
+	}
+
+	if serviceConfig.ExpirySweepInterval > 0 {
+		go jobs.Every(ctx, "expired post sweep", serviceConfig.ExpirySweepInterval, func(ctx context.Context) error {
+			purged, err := service.PurgeExpiredPosts()
+			if purged > 0 {
+				log.Printf("Purged %d expired posts", purged)
+			}
+			return err
+		})
 	}
 
-	c := cors.New(cors.Options{
-		AllowedOrigins:   allowedOrigins,
-		AllowCredentials: true,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
-	})
+	go func() {
+		fmt.Printf("Server listening on %s...\n", cfg.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalln("Server failed", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	fmt.Println("Shutting down, draining in-flight requests...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
 
-	corsHandler := c.Handler(r)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("Graceful shutdown failed", err)
+	}
 
-	fmt.Println("Server listening on port 8080...")
-	http.ListenAndServe(":8080", corsHandler)
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Println("Failed to close database pool", err)
+		}
+	}
 
+	fmt.Println("Server stopped")
 }
@@ -4,13 +4,13 @@ import (
 	"os"
 	"time"
 
-	"hyperlocal/internal/entities"
-
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// Connect opens the database pool. The schema is managed by the versioned
+// migrations in this package, see MigrateUp.
 func Connect() *gorm.DB {
 	connectionString := os.Getenv("DATABASE_URL")
 	if connectionString == "" {
@@ -19,6 +19,9 @@ func Connect() *gorm.DB {
 
 	db, err := gorm.Open(postgres.Open(connectionString), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Report constraint violations as gorm.ErrDuplicatedKey and
+		// gorm.ErrForeignKeyViolated so models can map them to domain errors
+		TranslateError: true,
 	})
 	if err != nil {
 		panic("failed to connect database: " + err.Error())
@@ -37,15 +40,9 @@ func Connect() *gorm.DB {
 		panic("failed to ping database: " + err.Error())
 	}
 
-	if err := db.AutoMigrate(&entities.User{}, &entities.Post{}, &entities.Comment{}, &entities.Report{}, &entities.UserPostVote{}, &entities.RefreshToken{}); err != nil {
-		panic("failed to auto-migrate database: " + err.Error())
-	}
-
 	// if err := SeedData(db); err != nil {
 	// 	panic("failed to seed database: " + err.Error())
 	// }
 
-	db.Exec("CREATE EXTENSION IF NOT EXISTS postgis")
-
 	return db
 }
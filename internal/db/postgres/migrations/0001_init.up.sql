@@ -0,0 +1,55 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the old
+-- AutoMigrate bootstrap adopt this migration unchanged.
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS users (
+    id            uuid PRIMARY KEY,
+    username      text UNIQUE,
+    password_hash text,
+    is_banned     boolean DEFAULT false,
+    created_at    timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id         uuid PRIMARY KEY,
+    user_id    uuid CONSTRAINT fk_posts_user REFERENCES users (id),
+    content    text,
+    latitude   double precision,
+    longitude  double precision,
+    upvotes    bigint DEFAULT 0,
+    downvotes  bigint DEFAULT 0,
+    is_flagged boolean DEFAULT false,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+    id         uuid PRIMARY KEY,
+    post_id    uuid CONSTRAINT fk_comments_post REFERENCES posts (id),
+    user_id    uuid CONSTRAINT fk_comments_user REFERENCES users (id),
+    content    text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS reports (
+    id         uuid PRIMARY KEY,
+    post_id    uuid CONSTRAINT fk_reports_post REFERENCES posts (id),
+    user_id    uuid CONSTRAINT fk_reports_user REFERENCES users (id),
+    reason     text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS user_post_votes (
+    id         uuid PRIMARY KEY,
+    user_id    uuid CONSTRAINT fk_user_post_votes_user REFERENCES users (id),
+    post_id    uuid CONSTRAINT fk_user_post_votes_post REFERENCES posts (id),
+    vote_type  text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         uuid PRIMARY KEY,
+    user_id    uuid CONSTRAINT fk_refresh_tokens_user REFERENCES users (id),
+    token      text UNIQUE,
+    expires_at timestamptz,
+    created_at timestamptz
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_location;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS location;
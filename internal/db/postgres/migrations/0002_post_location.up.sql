@@ -0,0 +1,7 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS location geography(Point, 4326);
+
+UPDATE posts
+SET location = ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography
+WHERE location IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_location ON posts USING GIST (location);
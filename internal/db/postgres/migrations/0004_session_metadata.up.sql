@@ -0,0 +1,5 @@
+ALTER TABLE refresh_tokens
+    ADD COLUMN user_agent text NOT NULL DEFAULT '',
+    ADD COLUMN ip_address text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,14 @@
+-- Raw tokens cannot be recovered from their hashes, so rolling back logs
+-- everyone out.
+DROP TABLE IF EXISTS security_events;
+
+DELETE FROM refresh_tokens;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN token text UNIQUE,
+    DROP COLUMN IF EXISTS token_hash,
+    DROP COLUMN IF EXISTS family_id,
+    DROP COLUMN IF EXISTS rotated_at;
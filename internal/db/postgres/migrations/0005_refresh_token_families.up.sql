@@ -0,0 +1,31 @@
+-- Refresh tokens are stored as SHA-256 hashes and grouped into rotation
+-- families. Rotated tokens are kept (with rotated_at set) until they expire
+-- so that replaying one can be detected.
+ALTER TABLE refresh_tokens
+    ADD COLUMN token_hash text,
+    ADD COLUMN family_id uuid,
+    ADD COLUMN rotated_at timestamptz;
+
+UPDATE refresh_tokens
+SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    family_id  = id;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN token_hash SET NOT NULL,
+    ALTER COLUMN family_id SET NOT NULL,
+    DROP COLUMN token;
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+CREATE TABLE security_events (
+    id         uuid PRIMARY KEY,
+    user_id    uuid CONSTRAINT fk_security_events_user REFERENCES users (id),
+    type       text NOT NULL,
+    details    text NOT NULL DEFAULT '',
+    ip_address text NOT NULL DEFAULT '',
+    user_agent text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_security_events_user_id ON security_events (user_id);
@@ -0,0 +1,7 @@
+-- Failed login tracking. key is "user:<username>" or "ip:<address>".
+CREATE TABLE login_throttles (
+    key             text PRIMARY KEY,
+    failures        integer NOT NULL DEFAULT 0,
+    last_failure_at timestamptz NOT NULL,
+    locked_until    timestamptz
+);
@@ -0,0 +1,13 @@
+ALTER TABLE posts ADD COLUMN edited_at timestamptz;
+
+-- Prior contents of edited posts. content is the text as it was before the edit
+-- made by edited_by at created_at.
+CREATE TABLE post_revisions (
+    id         uuid PRIMARY KEY,
+    post_id    uuid NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    content    text NOT NULL,
+    edited_by  uuid NOT NULL REFERENCES users (id),
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_post_revisions_post_id ON post_revisions (post_id, created_at);
@@ -0,0 +1,11 @@
+-- Deleted posts and comments are kept for moderators. deleted_by is the author
+-- or the moderator who removed the row.
+ALTER TABLE posts
+    ADD COLUMN deleted_at      timestamptz,
+    ADD COLUMN deleted_by      uuid CONSTRAINT fk_posts_deleted_by REFERENCES users (id),
+    ADD COLUMN deletion_reason text NOT NULL DEFAULT '';
+
+ALTER TABLE comments
+    ADD COLUMN deleted_at      timestamptz,
+    ADD COLUMN deleted_by      uuid CONSTRAINT fk_comments_deleted_by REFERENCES users (id),
+    ADD COLUMN deletion_reason text NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- Threaded replies. depth is 0 for top-level comments and reply_count counts
+-- replies that have not been deleted.
+ALTER TABLE comments
+    ADD COLUMN parent_id   uuid CONSTRAINT fk_comments_parent REFERENCES comments (id),
+    ADD COLUMN depth       integer NOT NULL DEFAULT 0,
+    ADD COLUMN reply_count integer NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_comments_post_id ON comments (post_id, created_at);
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
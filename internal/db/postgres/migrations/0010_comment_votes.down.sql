@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_comment_votes;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS downvotes,
+    DROP COLUMN IF EXISTS upvotes;
@@ -0,0 +1,12 @@
+ALTER TABLE comments
+    ADD COLUMN upvotes   integer NOT NULL DEFAULT 0,
+    ADD COLUMN downvotes integer NOT NULL DEFAULT 0;
+
+CREATE TABLE user_comment_votes (
+    id         uuid PRIMARY KEY,
+    user_id    uuid NOT NULL CONSTRAINT fk_user_comment_votes_user REFERENCES users (id),
+    comment_id uuid NOT NULL CONSTRAINT fk_user_comment_votes_comment REFERENCES comments (id),
+    vote_type  text NOT NULL CHECK (vote_type IN ('upvote', 'downvote')),
+    created_at timestamptz NOT NULL,
+    CONSTRAINT uq_user_comment_votes UNIQUE (user_id, comment_id)
+);
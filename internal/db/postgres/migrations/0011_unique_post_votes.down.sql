@@ -0,0 +1,2 @@
+ALTER TABLE user_post_votes
+    DROP CONSTRAINT IF EXISTS uq_user_post_votes;
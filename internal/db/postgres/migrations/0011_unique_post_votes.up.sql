@@ -0,0 +1,25 @@
+-- The old voting path read the existing vote outside a transaction, so
+-- concurrent requests could record the same user's vote twice. Keep each
+-- user's first vote and drop the rest.
+DELETE FROM user_post_votes v
+USING user_post_votes older
+WHERE v.user_id = older.user_id
+    AND v.post_id = older.post_id
+    AND (older.created_at, older.id) < (v.created_at, v.id);
+
+ALTER TABLE user_post_votes
+    ADD CONSTRAINT uq_user_post_votes UNIQUE (user_id, post_id);
+
+-- Recount the votes the duplicates and untracked increments skewed
+UPDATE posts
+SET upvotes = counts.upvotes, downvotes = counts.downvotes
+FROM (
+    SELECT posts.id,
+        COUNT(v.id) FILTER (WHERE v.vote_type = 'upvote') AS upvotes,
+        COUNT(v.id) FILTER (WHERE v.vote_type = 'downvote') AS downvotes
+    FROM posts
+    LEFT JOIN user_post_votes v ON v.post_id = posts.id
+    GROUP BY posts.id
+) counts
+WHERE posts.id = counts.id
+    AND (posts.upvotes IS DISTINCT FROM counts.upvotes OR posts.downvotes IS DISTINCT FROM counts.downvotes);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_posts_tags;
+DROP INDEX IF EXISTS idx_posts_category;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS category;
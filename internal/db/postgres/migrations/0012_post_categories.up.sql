@@ -0,0 +1,19 @@
+-- Existing posts predate categories and are filed under general. The service
+-- validates categories against enums.Categories, so adding one needs no migration.
+ALTER TABLE posts
+    ADD COLUMN category text NOT NULL DEFAULT 'general',
+    ADD COLUMN tags text[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE posts ALTER COLUMN category DROP DEFAULT;
+
+-- Backfill hashtags the same way the service extracts them: lowercased and deduplicated
+UPDATE posts
+SET tags = ARRAY(
+    SELECT DISTINCT lower(m[1])
+    FROM regexp_matches(content, '#([[:alnum:]_]{1,50})', 'g') AS m
+    LIMIT 10
+)
+WHERE content LIKE '%#%';
+
+CREATE INDEX idx_posts_category ON posts (category);
+CREATE INDEX idx_posts_tags ON posts USING gin (tags);
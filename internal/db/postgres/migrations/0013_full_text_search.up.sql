@@ -0,0 +1,14 @@
+-- Full-text search. Each post is stemmed with its own text search
+-- configuration, and comments use the configuration of their post.
+ALTER TABLE posts
+    ADD COLUMN language regconfig NOT NULL DEFAULT 'english',
+    ADD COLUMN search_vector tsvector
+        GENERATED ALWAYS AS (to_tsvector(language, coalesce(content, ''))) STORED;
+
+ALTER TABLE comments
+    ADD COLUMN language regconfig NOT NULL DEFAULT 'english',
+    ADD COLUMN search_vector tsvector
+        GENERATED ALWAYS AS (to_tsvector(language, coalesce(content, ''))) STORED;
+
+CREATE INDEX idx_posts_search_vector ON posts USING gin (search_vector);
+CREATE INDEX idx_comments_search_vector ON comments USING gin (search_vector);
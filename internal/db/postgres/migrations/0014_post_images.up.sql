@@ -0,0 +1,14 @@
+-- Images attached to posts. key and thumbnail_key locate the stored blobs;
+-- position keeps the order the author uploaded them in.
+CREATE TABLE post_images (
+    id            uuid PRIMARY KEY,
+    post_id       uuid NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    position      integer NOT NULL,
+    key           text NOT NULL,
+    thumbnail_key text NOT NULL,
+    content_type  text NOT NULL,
+    width         integer NOT NULL,
+    height        integer NOT NULL,
+    created_at    timestamptz NOT NULL,
+    CONSTRAINT uq_post_images_position UNIQUE (post_id, position)
+);
@@ -0,0 +1,5 @@
+-- How closely a post's stored location matches where it was made. Existing
+-- posts were stored as sent by the client.
+ALTER TABLE posts
+    ADD COLUMN location_precision text NOT NULL DEFAULT 'exact'
+        CONSTRAINT chk_posts_location_precision CHECK (location_precision IN ('exact', 'street', 'neighbourhood'));
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS post_participants;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS pseudonym,
+    DROP COLUMN IF EXISTS is_anonymous;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS participant_count,
+    DROP COLUMN IF EXISTS pseudonym,
+    DROP COLUMN IF EXISTS is_anonymous;
@@ -0,0 +1,18 @@
+ALTER TABLE posts
+    ADD COLUMN is_anonymous boolean NOT NULL DEFAULT false,
+    ADD COLUMN pseudonym integer NOT NULL DEFAULT 0,
+    ADD COLUMN participant_count integer NOT NULL DEFAULT 0;
+
+ALTER TABLE comments
+    ADD COLUMN is_anonymous boolean NOT NULL DEFAULT false,
+    ADD COLUMN pseudonym integer NOT NULL DEFAULT 0;
+
+-- Pseudonyms of anonymous participants, numbered per post from 1 in the
+-- order they first wrote anonymously. A user keeps their number on a post.
+CREATE TABLE post_participants (
+    post_id   uuid NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    user_id   uuid NOT NULL REFERENCES users (id),
+    pseudonym integer NOT NULL,
+    PRIMARY KEY (post_id, user_id),
+    CONSTRAINT uq_post_participants_pseudonym UNIQUE (post_id, pseudonym)
+);
@@ -0,0 +1,5 @@
+-- When a post stops being shown. Existing posts never expire.
+ALTER TABLE posts ADD COLUMN expires_at timestamptz;
+
+-- Lets the sweeper find expired posts without scanning the table
+CREATE INDEX idx_posts_expires_at ON posts (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- The original coordinates are gone, legacy posts stay snapped to the street grid
+SELECT 1;
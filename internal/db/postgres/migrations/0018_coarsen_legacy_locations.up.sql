@@ -0,0 +1,30 @@
+-- Posts made before 0015 were stored as sent by the client and marked exact.
+-- Snap them to the street grid the default precision uses, the same way the
+-- service fuzzes new posts: 100m rows of latitude, with longitude cells sized
+-- at the snapped latitude of each row. Posts made since then chose their own
+-- precision and are left alone. A MIN_LOCATION_PRECISION coarser than street
+-- is applied to every post by the service at startup.
+WITH legacy AS (
+    SELECT id, longitude,
+           GREATEST(-90, LEAST(90, (floor(latitude / (100 / 111320.0)) + 0.5) * (100 / 111320.0))) AS lat
+    FROM posts
+    WHERE location_precision = 'exact'
+        AND latitude IS NOT NULL AND longitude IS NOT NULL
+        AND created_at < (SELECT applied_at FROM schema_migrations WHERE version = 15)
+), cells AS (
+    SELECT id, lat,
+           (floor(longitude / ((100 / 111320.0) / GREATEST(cos(radians(lat)), 0.01))) + 0.5)
+               * ((100 / 111320.0) / GREATEST(cos(radians(lat)), 0.01)) AS lng
+    FROM legacy
+), snapped AS (
+    SELECT id, lat,
+           CASE WHEN lng > 180 THEN lng - 360 WHEN lng < -180 THEN lng + 360 ELSE lng END AS lng
+    FROM cells
+)
+UPDATE posts
+SET latitude = snapped.lat,
+    longitude = snapped.lng,
+    location = ST_SetSRID(ST_MakePoint(snapped.lng, snapped.lat), 4326)::geography,
+    location_precision = 'street'
+FROM snapped
+WHERE posts.id = snapped.id;
@@ -0,0 +1,3 @@
+-- Media requests look images up by blob key to check their post is still visible
+CREATE INDEX idx_post_images_key ON post_images (key);
+CREATE INDEX idx_post_images_thumbnail_key ON post_images (thumbnail_key);
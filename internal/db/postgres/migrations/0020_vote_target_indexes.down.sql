@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_comment_votes_comment;
+DROP INDEX IF EXISTS idx_user_post_votes_post;
@@ -0,0 +1,4 @@
+-- The unique constraints lead with user_id; vote recounts and purges look
+-- votes up by what was voted on
+CREATE INDEX idx_user_post_votes_post ON user_post_votes (post_id);
+CREATE INDEX idx_user_comment_votes_comment ON user_comment_votes (comment_id);
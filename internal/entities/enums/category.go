@@ -0,0 +1,33 @@
+package enums
+
+// Category is the curated topic of a post
+type Category string
+
+const (
+	CategoryGeneral        Category = "general"
+	CategoryLostAndFound   Category = "lost_and_found"
+	CategorySafetyAlert    Category = "safety_alert"
+	CategoryEvent          Category = "event"
+	CategoryRecommendation Category = "recommendation"
+	CategoryForSale        Category = "for_sale"
+)
+
+// Categories lists every category in display order
+var Categories = []Category{
+	CategoryGeneral,
+	CategoryLostAndFound,
+	CategorySafetyAlert,
+	CategoryEvent,
+	CategoryRecommendation,
+	CategoryForSale,
+}
+
+// IsValid reports whether c is a known category
+func (c Category) IsValid() bool {
+	for _, category := range Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
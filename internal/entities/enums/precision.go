@@ -0,0 +1,48 @@
+package enums
+
+// LocationPrecision is how closely the stored location of a post matches
+// where it was made
+type LocationPrecision string
+
+const (
+	PrecisionExact         LocationPrecision = "exact"
+	PrecisionStreet        LocationPrecision = "street"
+	PrecisionNeighbourhood LocationPrecision = "neighbourhood"
+)
+
+// LocationPrecisions lists every precision from finest to coarsest
+var LocationPrecisions = []LocationPrecision{
+	PrecisionExact,
+	PrecisionStreet,
+	PrecisionNeighbourhood,
+}
+
+// IsValid reports whether p is a known precision
+func (p LocationPrecision) IsValid() bool {
+	return p.rank() >= 0
+}
+
+// Coarser returns whichever of p and q reveals less about the location
+func (p LocationPrecision) Coarser(q LocationPrecision) LocationPrecision {
+	if q.rank() > p.rank() {
+		return q
+	}
+	return p
+}
+
+// Finer lists the precisions that reveal more about the location than p
+func (p LocationPrecision) Finer() []LocationPrecision {
+	if p.rank() < 0 {
+		return nil
+	}
+	return LocationPrecisions[:p.rank()]
+}
+
+func (p LocationPrecision) rank() int {
+	for i, precision := range LocationPrecisions {
+		if p == precision {
+			return i
+		}
+	}
+	return -1
+}
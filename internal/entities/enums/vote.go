@@ -0,0 +1,18 @@
+package enums
+
+// VoteType is the direction of a vote on a post or comment
+type VoteType string
+
+const (
+	VoteUp   VoteType = "upvote"
+	VoteDown VoteType = "downvote"
+)
+
+// IsValid reports whether v is a known vote type
+func (v VoteType) IsValid() bool {
+	switch v {
+	case VoteUp, VoteDown:
+		return true
+	}
+	return false
+}
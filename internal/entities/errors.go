@@ -1,11 +1,143 @@
 package entities
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
+// Error kinds. Every domain error wraps one of these, so callers can branch
+// on the kind with errors.Is and map it to a status code.
 var (
-	ErrBookNotFound = errors.New("book not found")
+	ErrNotFound = errors.New("not found")
+
+	ErrConflict = errors.New("conflict")
+
+	ErrForbidden = errors.New("forbidden")
+
+	ErrUnauthorized = errors.New("unauthorized")
+
+	ErrValidation = errors.New("validation failed")
+
+	ErrRateLimited = errors.New("rate limited")
+
+	ErrBanned = errors.New("banned")
+)
+
+// Error is a domain error with a stable machine readable code
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	// Details holds per-field messages for validation errors
+	Details map[string]string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// NewError creates a domain error of the given kind
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// NewValidationError creates a validation error with per-field messages
+func NewValidationError(message string, details map[string]string) *Error {
+	return &Error{Kind: ErrValidation, Code: "validation_failed", Message: message, Details: details}
+}
+
+var (
+	ErrUserNotFound = NewError(ErrNotFound, "user_not_found", "user not found")
+
+	ErrPostNotFound = NewError(ErrNotFound, "post_not_found", "post not found")
+
+	ErrCommentNotFound = NewError(ErrNotFound, "comment_not_found", "comment not found")
+
+	ErrVoteNotFound = NewError(ErrNotFound, "vote_not_found", "you have not voted on this")
+
+	ErrSessionNotFound = NewError(ErrNotFound, "session_not_found", "session not found")
+
+	ErrUsernameTaken = NewError(ErrConflict, "username_taken", "username already taken")
+
+	ErrAlreadyVoted = NewError(ErrConflict, "already_voted", "you have already cast this vote")
+
+	ErrInvalidCredentials = NewError(ErrUnauthorized, "invalid_credentials", "invalid credentials")
+
+	ErrAuthRequired = NewError(ErrUnauthorized, "authentication_required", "authentication required")
+
+	ErrInvalidToken = NewError(ErrUnauthorized, "invalid_token", "invalid or expired token")
+
+	ErrInvalidRefreshToken = NewError(ErrUnauthorized, "invalid_refresh_token", "invalid refresh token")
 
-	ErrUserNotFound = errors.New("user not found")
+	ErrRefreshTokenExpired = NewError(ErrUnauthorized, "refresh_token_expired", "refresh token expired")
 
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrRefreshTokenReused = NewError(ErrUnauthorized, "refresh_token_reused", "refresh token reuse detected, session revoked")
+
+	ErrAccountBanned = NewError(ErrBanned, "account_banned", "account is banned")
+
+	ErrInsufficientRole = NewError(ErrForbidden, "insufficient_permissions", "insufficient permissions")
+
+	ErrCannotChangeOwnRole = NewError(ErrForbidden, "cannot_change_own_role", "cannot change your own role")
+
+	ErrNotAuthor = NewError(ErrForbidden, "not_author", "only the author can modify this")
+
+	ErrEditWindowClosed = NewError(ErrForbidden, "edit_window_closed", "the edit window for this post has closed")
+
+	ErrRateLimitExceeded = NewError(ErrRateLimited, "rate_limit_exceeded", "rate limit exceeded")
+
+	ErrLoginThrottled = NewError(ErrRateLimited, "login_throttled", "too many failed login attempts")
+
+	ErrInvalidRole = NewError(ErrValidation, "invalid_role", "role must be one of user, moderator, admin")
+
+	ErrInvalidCursor = NewError(ErrValidation, "invalid_cursor", "invalid cursor")
+
+	ErrInvalidSort = NewError(ErrValidation, "invalid_sort", "sort must be one of new, top, hot")
+
+	ErrInvalidWindow = NewError(ErrValidation, "invalid_window", "window must be one of day, week, month, all")
+
+	ErrInvalidCategory = NewError(ErrValidation, "invalid_category", "unknown category")
+
+	ErrInvalidExpiry = NewError(ErrValidation, "invalid_expiry", "expires_in must be between one hour and 90 days")
+
+	ErrInvalidTag = NewError(ErrValidation, "invalid_tag", "tags may only contain letters, digits and underscores")
+
+	ErrInvalidSearchQuery = NewError(ErrValidation, "invalid_search_query", "search query must be 1 to 200 characters")
+
+	ErrInvalidLanguage = NewError(ErrValidation, "invalid_language", "unsupported language")
+
+	ErrInvalidCommentSort = NewError(ErrValidation, "invalid_sort", "sort must be one of new, top")
+
+	ErrInvalidParentComment = NewError(ErrValidation, "invalid_parent_comment", "parent comment not found on this post")
+
+	ErrCommentTooDeep = NewError(ErrValidation, "comment_too_deep", "replies cannot be nested this deeply")
+
+	ErrTooManyImages = NewError(ErrValidation, "too_many_images", "too many images attached")
+
+	ErrImageTooLarge = NewError(ErrValidation, "image_too_large", "image is too large")
+
+	ErrUnsupportedImage = NewError(ErrValidation, "unsupported_image", "images must be JPEG, PNG, GIF or WebP")
 )
+
+// LoginThrottledError reports how long a client must wait before trying to log in again
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	// AccountLocked is set when the username, rather than the client IP, is locked out
+	AccountLocked bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	wait := e.RetryAfter.Round(time.Second)
+	if e.AccountLocked {
+		return fmt.Sprintf("account temporarily locked after too many failed login attempts, try again in %s", wait)
+	}
+	return fmt.Sprintf("too many failed login attempts, try again in %s", wait)
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
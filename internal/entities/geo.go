@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GeoPoint is a WGS84 point stored as a PostGIS geography(Point,4326)
+type GeoPoint struct {
+	Longitude float64
+	Latitude  float64
+}
+
+// GormValue writes the point as a geography expression
+func (p GeoPoint) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return clause.Expr{
+		SQL:  "ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography",
+		Vars: []interface{}{p.Longitude, p.Latitude},
+	}
+}
+
+// Scan reads a point from the hex-encoded EWKB that PostGIS returns
+func (p *GeoPoint) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*p = GeoPoint{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("unsupported geography value %T", value)
+	}
+
+	wkb := make([]byte, hex.DecodedLen(len(raw)))
+	if _, err := hex.Decode(wkb, raw); err != nil {
+		return err
+	}
+
+	if len(wkb) < 5 {
+		return errors.New("geography value too short")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if wkb[0] == 0 {
+		order = binary.BigEndian
+	}
+
+	geomType := order.Uint32(wkb[1:5])
+	offset := 5
+
+	// EWKB flags an embedded SRID with the 0x20000000 bit
+	if geomType&0x20000000 != 0 {
+		offset += 4
+	}
+	if geomType&0xFFFF != 1 {
+		return errors.New("geography value is not a point")
+	}
+	if len(wkb) < offset+16 {
+		return errors.New("geography value too short")
+	}
+
+	p.Longitude = math.Float64frombits(order.Uint64(wkb[offset : offset+8]))
+	p.Latitude = math.Float64frombits(order.Uint64(wkb[offset+8 : offset+16]))
+	return nil
+}
@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Tags is a list of hashtags stored as a Postgres text[]. Tags only contain
+// letters, digits and underscores, so the array literal needs no escaping.
+type Tags []string
+
+// Value writes the tags as an array literal, quoting every element so a tag
+// such as "null" is not read back as NULL
+func (t Tags) Value() (driver.Value, error) {
+	quoted := make([]string, len(t))
+	for i, tag := range t {
+		quoted[i] = `"` + tag + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Scan reads the text form of a Postgres array
+func (t *Tags) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		*t = Tags{}
+		return nil
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported tags value %T", value)
+	}
+
+	raw = strings.TrimSuffix(strings.TrimPrefix(raw, "{"), "}")
+	if raw == "" {
+		*t = Tags{}
+		return nil
+	}
+
+	elements := strings.Split(raw, ",")
+	tags := make(Tags, len(elements))
+	for i, element := range elements {
+		tags[i] = strings.Trim(element, `"`)
+	}
+	*t = tags
+	return nil
+}
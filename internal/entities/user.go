@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"hyperlocal/internal/entities/enums"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,7 +12,8 @@ type User struct {
 	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
 	Username     *string   `gorm:"unique"`
 	PasswordHash string
-	IsBanned     bool `gorm:"default:false"`
+	Role         enums.Role `gorm:"default:user"`
+	IsBanned     bool       `gorm:"default:false"`
 	CreatedAt    time.Time
 }
 
@@ -20,13 +22,80 @@ type Post struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
 	UserID    uuid.UUID `gorm:"type:uuid"`
 	Content   string
-	Latitude  float64
-	Longitude float64
-	Upvotes   int  `gorm:"default:0"`
-	Downvotes int  `gorm:"default:0"`
-	IsFlagged bool `gorm:"default:false"`
+	Category  enums.Category
+	Tags      Tags   `gorm:"type:text[]"`
+	Language  string `gorm:"default:english"` // text search configuration
+	Upvotes   int    `gorm:"default:0"`
+	Downvotes int    `gorm:"default:0"`
+	IsFlagged bool   `gorm:"default:false"`
 	CreatedAt time.Time
+	EditedAt  *time.Time
 	User      User `gorm:"foreignKey:UserID"`
+
+	// ExpiresAt hides the post from then on; it is purged some time later
+	ExpiresAt *time.Time
+
+	// The stored location is already fuzzed to LocationPrecision and is
+	// never serialised; responses only carry distances
+	Latitude          float64                 `json:"-"`
+	Longitude         float64                 `json:"-"`
+	Location          GeoPoint                `gorm:"type:geography(Point,4326);index:idx_posts_location,type:gist" json:"-"`
+	LocationPrecision enums.LocationPrecision `gorm:"default:exact"`
+
+	// IsAnonymous hides the author from other users, who see the post as
+	// posted by Neighbour #Pseudonym. ParticipantCount is the last pseudonym
+	// handed out on the post.
+	IsAnonymous      bool `gorm:"default:false"`
+	Pseudonym        int
+	ParticipantCount int `gorm:"default:0"`
+
+	// Images are the attachments of the post, ordered by Position
+	Images []PostImage `gorm:"foreignKey:PostID"`
+
+	// DeletedAt is set when the author or a moderator removes the post
+	DeletedAt      *time.Time
+	DeletedBy      *uuid.UUID `gorm:"type:uuid"`
+	DeletionReason string
+
+	// DistanceMeters and Score are computed by feed queries and are not stored
+	DistanceMeters float64 `gorm:"->;-:migration"`
+	Score          float64 `gorm:"->;-:migration"`
+
+	// Snippet and MatchedCommentID are computed by search queries: the
+	// highlighted text that matched, and the comment it came from if any
+	Snippet          string     `gorm:"->;-:migration"`
+	MatchedCommentID *uuid.UUID `gorm:"->;-:migration"`
+}
+
+// PostRevision keeps the content a post had before an edit
+type PostRevision struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	PostID    uuid.UUID `gorm:"type:uuid"`
+	Content   string
+	EditedBy  uuid.UUID `gorm:"type:uuid"`
+	CreatedAt time.Time
+}
+
+// PostImage is an image attached to a post. Key and ThumbnailKey locate the
+// re-encoded image and its thumbnail in the blob store.
+type PostImage struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	PostID       uuid.UUID `gorm:"type:uuid"`
+	Position     int
+	Key          string
+	ThumbnailKey string
+	ContentType  string
+	Width        int
+	Height       int
+	CreatedAt    time.Time
+}
+
+// PostParticipant is the pseudonym a user writes under anonymously on a
+// post, shared by their anonymous post and comments there
+type PostParticipant struct {
+	PostID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Pseudonym int
 }
 
 // Comment represents a comment on a post
@@ -35,9 +104,27 @@ type Comment struct {
 	PostID    uuid.UUID `gorm:"type:uuid"`
 	UserID    uuid.UUID `gorm:"type:uuid"`
 	Content   string
+	Language  string `gorm:"default:english"`
 	CreatedAt time.Time
 	User      User `gorm:"foreignKey:UserID"`
 	Post      Post `gorm:"foreignKey:PostID"`
+
+	// IsAnonymous hides the author behind their pseudonym on the post
+	IsAnonymous bool `gorm:"default:false"`
+	Pseudonym   int
+
+	// ParentID is set on replies. Depth is 0 for top-level comments.
+	ParentID   *uuid.UUID `gorm:"type:uuid"`
+	Depth      int
+	ReplyCount int `gorm:"default:0"`
+
+	Upvotes   int `gorm:"default:0"`
+	Downvotes int `gorm:"default:0"`
+
+	// DeletedAt is set when the author removes the comment
+	DeletedAt      *time.Time
+	DeletedBy      *uuid.UUID `gorm:"type:uuid"`
+	DeletionReason string
 }
 
 // Report represents a report of a post
@@ -56,18 +143,58 @@ type UserPostVote struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
 	UserID    uuid.UUID `gorm:"type:uuid"`
 	PostID    uuid.UUID `gorm:"type:uuid"`
-	VoteType  string    // "upvote" or "downvote"
+	VoteType  enums.VoteType
 	CreatedAt time.Time
 	User      User `gorm:"foreignKey:UserID"`
 	Post      Post `gorm:"foreignKey:PostID"`
 }
 
-// RefreshToken stores refresh tokens for users
+// UserCommentVote tracks user votes on comments to prevent multiple votes
+type UserCommentVote struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid"`
+	CommentID uuid.UUID `gorm:"type:uuid"`
+	VoteType  enums.VoteType
+	CreatedAt time.Time
+	User      User    `gorm:"foreignKey:UserID"`
+	Comment   Comment `gorm:"foreignKey:CommentID"`
+}
+
+// RefreshToken stores refresh tokens for users. Only the SHA-256 hash of the
+// token is kept. Tokens issued by rotating one another share a FamilyID, which
+// also identifies the login session.
 type RefreshToken struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
 	UserID    uuid.UUID `gorm:"type:uuid"`
-	Token     string    `gorm:"unique"`
+	FamilyID  uuid.UUID `gorm:"type:uuid;index"`
+	TokenHash string    `gorm:"unique"`
+	UserAgent string
+	IPAddress string
 	ExpiresAt time.Time
+	RotatedAt *time.Time
 	CreatedAt time.Time
 	User      User `gorm:"foreignKey:UserID"`
+
+	// SessionStartedAt is when the first token of the family was issued,
+	// computed by session queries and not stored
+	SessionStartedAt time.Time `gorm:"->;-:migration"`
+}
+
+// SecurityEvent records suspicious account activity such as refresh token reuse
+type SecurityEvent struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid"`
+	Type      string
+	Details   string
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+}
+
+// LoginThrottle counts recent failed logins for a username or client IP
+type LoginThrottle struct {
+	Key           string `gorm:"primary_key"`
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
 }
@@ -0,0 +1,42 @@
+// Package env reads optional settings from environment variables. An unset
+// variable keeps the caller's default; a set but invalid one is an error, so
+// a typo in the configuration stops the server instead of being ignored.
+package env
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Duration overwrites d with the non-negative duration, such as "30m", in the
+// environment variable, if set
+func Duration(key string, d *time.Duration) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	*d = parsed
+	return nil
+}
+
+// Int overwrites n with the non-negative integer in the environment variable, if set
+func Int(key string, n *int64) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("invalid %s %q", key, value)
+	}
+	*n = parsed
+	return nil
+}
@@ -0,0 +1,150 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hyperlocal/internal/entities"
+	"log"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorResponse is the JSON body of every error reply
+type ErrorResponse struct {
+	Code      string            `json:"code"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// MessageResponse is the JSON body of a successful reply that carries only a message
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// JSON writes v as a JSON response with the given status
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Error writes err as an ErrorResponse. Domain errors are mapped to a status
+// by their kind; anything else is logged and reported as an opaque 500 so
+// database errors never reach the client.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	body := ErrorResponse{
+		Code:      "internal_error",
+		Message:   "internal server error",
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+	status := http.StatusInternalServerError
+
+	var domainErr *entities.Error
+	if errors.As(err, &domainErr) {
+		body.Code = domainErr.Code
+		body.Message = domainErr.Message
+		body.Details = domainErr.Details
+		status = statusFor(domainErr)
+	} else {
+		log.Printf("request %s failed: %v", body.RequestID, err)
+	}
+
+	var throttled *entities.LoginThrottledError
+	if errors.As(err, &throttled) {
+		body.Message = throttled.Error()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		if throttled.AccountLocked {
+			body.Code = "account_locked"
+			status = http.StatusLocked
+		}
+	}
+
+	JSON(w, status, body)
+}
+
+// statusFor maps the kind of a domain error to an HTTP status
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, entities.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, entities.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, entities.ErrForbidden), errors.Is(err, entities.ErrBanned):
+		return http.StatusForbidden
+	case errors.Is(err, entities.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, entities.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, entities.ErrRateLimited):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// InvalidBody converts a JSON decoding error into a validation error
+func InvalidBody(err error) error {
+	return entities.NewValidationError("invalid request body", map[string]string{"body": err.Error()})
+}
+
+// Validation converts validator errors into a validation error with one
+// message per field, keyed by the field's JSON name
+func Validation(err error) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return entities.NewValidationError(err.Error(), nil)
+	}
+
+	details := make(map[string]string, len(fieldErrors))
+	for _, fe := range fieldErrors {
+		details[fe.Field()] = fieldMessage(fe)
+	}
+
+	return entities.NewValidationError("request validation failed", details)
+}
+
+// fieldMessage describes a failed validation rule in plain words
+func fieldMessage(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", fe.Param())
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
+
+// JSONTagName makes validator report fields by their JSON name, for use with
+// validator.RegisterTagNameFunc
+func JSONTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
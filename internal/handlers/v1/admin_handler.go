@@ -2,6 +2,10 @@ package v1
 
 import (
 	"encoding/json"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"hyperlocal/internal/handlers/response"
+	"hyperlocal/internal/services"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -16,19 +20,18 @@ import (
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {array} services.PostResponse
-// @Failure 401 {object} map[string]interface{}
-// @Failure 403 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /admin/flagged [get]
 func (h *handlerV1) GetFlaggedPosts(w http.ResponseWriter, r *http.Request) {
 	posts, err := h.Service.GetFlaggedPosts()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	response.JSON(w, http.StatusOK, posts)
 }
 
 // BanUser handles banning a user
@@ -39,27 +42,133 @@ func (h *handlerV1) GetFlaggedPosts(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "User ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 403 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /admin/users/{id}/ban [patch]
 func (h *handlerV1) BanUser(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from URL
 	userIDStr := chi.URLParam(r, "id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid user ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
 	if err := h.Service.BanUser(userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "User banned successfully"})
-}
\ No newline at end of file
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "User banned successfully"})
+}
+
+// SetUserRole handles granting a role to a user
+// @Summary Set a user's role
+// @Description Grant the user, moderator or admin role to a user (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body services.SetUserRoleRequest true "Role"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /admin/users/{id}/role [put]
+func (h *handlerV1) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var req services.SetUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.InvalidBody(err))
+		return
+	}
+
+	if err := h.Validate.Struct(req); err != nil {
+		response.Error(w, r, response.Validation(err))
+		return
+	}
+
+	h.updateUserRole(w, r, req.Role)
+}
+
+// RevokeUserRole handles revoking elevated permissions from a user
+// @Summary Revoke a user's role
+// @Description Reset a user to the default user role (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /admin/users/{id}/role [delete]
+func (h *handlerV1) RevokeUserRole(w http.ResponseWriter, r *http.Request) {
+	h.updateUserRole(w, r, string(enums.RoleUser))
+}
+
+// ClearLoginLockout handles lifting a login lockout
+// @Summary Clear a login lockout
+// @Description Unlock a user locked out by failed logins and reset the failure count (admin only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /admin/users/{id}/lockout [delete]
+func (h *handlerV1) ClearLoginLockout(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from URL
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid user ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	if err := h.Service.ClearLoginLockout(userID); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Login lockout cleared successfully"})
+}
+
+// updateUserRole applies a role change for the user in the URL on behalf of the caller
+func (h *handlerV1) updateUserRole(w http.ResponseWriter, r *http.Request, role string) {
+	// Get user ID from URL
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid user ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	// Get the acting admin from context
+	actorID := r.Context().Value("userID")
+	if actorID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.SetUserRole(actorID.(uuid.UUID), userID, role); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "User role updated to " + role})
+}
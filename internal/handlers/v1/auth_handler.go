@@ -2,8 +2,14 @@ package v1
 
 import (
 	"encoding/json"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/handlers/response"
 	"hyperlocal/internal/services"
+	"net"
 	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // Register handles user registration
@@ -14,30 +20,30 @@ import (
 // @Produce json
 // @Param request body services.RegisterRequest true "Registration details"
 // @Success 201 {object} services.TokenResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /auth/register [post]
 func (h *handlerV1) Register(w http.ResponseWriter, r *http.Request) {
 	var req services.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.InvalidBody(err))
 		return
 	}
 
 	if err := h.Validate.Struct(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.Validation(err))
 		return
 	}
 
-	tokens, err := h.Service.Register(req)
+	tokens, err := h.Service.Register(req, clientInfo(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(tokens)
+	response.JSON(w, http.StatusCreated, tokens)
 }
 
 // Login handles user login
@@ -48,30 +54,31 @@ func (h *handlerV1) Register(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body services.LoginRequest true "Login details"
 // @Success 200 {object} services.TokenResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 423 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /auth/login [post]
 func (h *handlerV1) Login(w http.ResponseWriter, r *http.Request) {
 	var req services.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.InvalidBody(err))
 		return
 	}
 
 	if err := h.Validate.Struct(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.Validation(err))
 		return
 	}
 
-	tokens, err := h.Service.Login(req)
+	tokens, err := h.Service.Login(req, clientInfo(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tokens)
+	response.JSON(w, http.StatusOK, tokens)
 }
 
 // RefreshToken handles token refresh
@@ -82,28 +89,179 @@ func (h *handlerV1) Login(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param request body services.RefreshTokenRequest true "Refresh token"
 // @Success 200 {object} services.TokenResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /auth/refresh [post]
 func (h *handlerV1) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req services.RefreshTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.InvalidBody(err))
+		return
+	}
+
+	if err := h.Validate.Struct(req); err != nil {
+		response.Error(w, r, response.Validation(err))
+		return
+	}
+
+	tokens, err := h.Service.RefreshToken(req, clientInfo(r))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tokens)
+}
+
+// Logout handles revoking the current refresh token
+// @Summary Logout
+// @Description Revoke a refresh token so it can no longer be used
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body services.LogoutRequest true "Refresh token"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /auth/logout [post]
+func (h *handlerV1) Logout(w http.ResponseWriter, r *http.Request) {
+	var req services.LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.InvalidBody(err))
 		return
 	}
 
 	if err := h.Validate.Struct(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.Validation(err))
 		return
 	}
 
-	tokens, err := h.Service.RefreshToken(req)
+	if err := h.Service.Logout(req); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Logged out successfully"})
+}
+
+// GetSessions handles listing the caller's active sessions
+// @Summary List sessions
+// @Description List the active sessions of the authenticated user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} services.SessionResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *handlerV1) GetSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	sessionID, _ := r.Context().Value("sessionID").(string)
+
+	sessions, err := h.Service.GetSessions(userID.(uuid.UUID), sessionID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tokens)
-}
\ No newline at end of file
+	response.JSON(w, http.StatusOK, sessions)
+}
+
+// RevokeSession handles revoking one of the caller's sessions
+// @Summary Revoke a session
+// @Description Log out a single session of the authenticated user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /auth/sessions/{id} [delete]
+func (h *handlerV1) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	// Get session ID from URL
+	sessionIDStr := chi.URLParam(r, "id")
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid session ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.RevokeSession(userID.(uuid.UUID), sessionID); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Session revoked successfully"})
+}
+
+// RevokeAllSessions handles logging the caller out everywhere
+// @Summary Log out everywhere
+// @Description Revoke every session of the authenticated user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.MessageResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /auth/sessions [delete]
+func (h *handlerV1) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.RevokeAllSessions(userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "All sessions revoked successfully"})
+}
+
+// JWKS handles publishing the token verification keys
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying hyperlocal access tokens, identified by kid
+// @Tags auth
+// @Produce json
+// @Success 200 {object} keys.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *handlerV1) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, http.StatusOK, h.Service.JWKS())
+}
+
+// clientInfo extracts the user agent and client IP recorded with a new session.
+// RemoteAddr has already been resolved from trusted proxy headers by the router.
+func clientInfo(r *http.Request) services.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return services.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
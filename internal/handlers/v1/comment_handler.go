@@ -2,6 +2,8 @@ package v1
 
 import (
 	"encoding/json"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/handlers/response"
 	"hyperlocal/internal/services"
 	"net/http"
 
@@ -11,7 +13,7 @@ import (
 
 // CreateComment handles comment creation
 // @Summary Create a new comment
-// @Description Create a new comment on a post
+// @Description Create a new comment on a post, or a reply to another comment when parent_id is set
 // @Tags comments
 // @Accept json
 // @Produce json
@@ -19,20 +21,20 @@ import (
 // @Param id path string true "Post ID"
 // @Param request body services.CreateCommentRequest true "Comment details"
 // @Success 201 {object} services.CommentResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts/{id}/comments [post]
 func (h *handlerV1) CreateComment(w http.ResponseWriter, r *http.Request) {
 	var req services.CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.InvalidBody(err))
 		return
 	}
 
 	if err := h.Validate.Struct(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.Validation(err))
 		return
 	}
 
@@ -40,57 +42,232 @@ func (h *handlerV1) CreateComment(w http.ResponseWriter, r *http.Request) {
 	postIDStr := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
 	// Get user ID from context
 	userID := r.Context().Value("userID")
 	if userID == nil {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		response.Error(w, r, entities.ErrAuthRequired)
 		return
 	}
 
 	comment, err := h.Service.CreateComment(req, postID, userID.(uuid.UUID))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(comment)
+	response.JSON(w, http.StatusCreated, comment)
 }
 
 // GetComments handles retrieving comments for a post
 // @Summary Get comments for a post
-// @Description Get all comments for a specific post
+// @Description Get the comments for a post as a flattened thread: each reply follows its parent and carries its depth
 // @Tags comments
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Post ID"
+// @Param sort query string false "Ordering: new (default) or top" Enums(new, top)
 // @Success 200 {array} services.CommentResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts/{id}/comments [get]
 func (h *handlerV1) GetComments(w http.ResponseWriter, r *http.Request) {
 	// Get post ID from URL
 	postIDStr := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
-	comments, err := h.Service.GetCommentsByPostID(postID)
+	comments, err := h.Service.GetCommentsByPostID(postID, r.URL.Query().Get("sort"))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(comments)
-}
\ No newline at end of file
+	response.JSON(w, http.StatusOK, comments)
+}
+
+// DeleteComment handles an author deleting their comment
+// @Summary Delete your comment
+// @Description Remove your own comment from the post. Moderators can still see it.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Param commentID path string true "Comment ID"
+// @Param request body services.DeleteRequest false "Optional reason"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/{id}/comments/{commentID} [delete]
+func (h *handlerV1) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	// Get post and comment IDs from URL
+	postID, commentID, err := commentIDs(r)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	var req services.DeleteRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if err := h.Validate.Struct(req); err != nil {
+		response.Error(w, r, response.Validation(err))
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.DeleteOwnComment(req, postID, commentID, userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Comment deleted successfully"})
+}
+
+// GetCommentsForModeration handles retrieving every comment on a post (moderator only)
+// @Summary Get comments for moderation
+// @Description Get all comments on a post, including removed ones and who removed them (moderator only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Success 200 {array} services.CommentResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /admin/posts/{id}/comments [get]
+func (h *handlerV1) GetCommentsForModeration(w http.ResponseWriter, r *http.Request) {
+	// Get post ID from URL
+	postIDStr := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	comments, err := h.Service.GetCommentsForModeration(postID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, comments)
+}
+
+// UpvoteComment handles upvoting a comment
+// @Summary Upvote a comment
+// @Description Upvote a comment on a post
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Param commentID path string true "Comment ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/{id}/comments/{commentID}/upvote [post]
+func (h *handlerV1) UpvoteComment(w http.ResponseWriter, r *http.Request) {
+	// Get post and comment IDs from URL
+	postID, commentID, err := commentIDs(r)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.UpvoteComment(postID, commentID, userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Comment upvoted successfully"})
+}
+
+// DownvoteComment handles downvoting a comment
+// @Summary Downvote a comment
+// @Description Downvote a comment on a post
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Param commentID path string true "Comment ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/{id}/comments/{commentID}/downvote [post]
+func (h *handlerV1) DownvoteComment(w http.ResponseWriter, r *http.Request) {
+	// Get post and comment IDs from URL
+	postID, commentID, err := commentIDs(r)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.DownvoteComment(postID, commentID, userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Comment downvoted successfully"})
+}
+
+// commentIDs parses the post and comment IDs of a comment route
+func commentIDs(r *http.Request) (postID, commentID uuid.UUID, err error) {
+	postID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"})
+	}
+
+	commentID, err = uuid.Parse(chi.URLParam(r, "commentID"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, entities.NewValidationError("invalid comment ID", map[string]string{"commentID": "must be a UUID"})
+	}
+
+	return postID, commentID, nil
+}
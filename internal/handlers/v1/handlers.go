@@ -1,7 +1,12 @@
 package v1
 
 import (
+	"encoding/json"
+	"errors"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/handlers/response"
 	"hyperlocal/internal/services"
+	"io"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -17,24 +22,59 @@ type HandlerV1 interface {
 	Register(w http.ResponseWriter, r *http.Request)
 	Login(w http.ResponseWriter, r *http.Request)
 	RefreshToken(w http.ResponseWriter, r *http.Request)
-	
+	Logout(w http.ResponseWriter, r *http.Request)
+	GetSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeAllSessions(w http.ResponseWriter, r *http.Request)
+	JWKS(w http.ResponseWriter, r *http.Request)
+
 	// Post handlers
 	CreatePost(w http.ResponseWriter, r *http.Request)
 	GetNearbyPosts(w http.ResponseWriter, r *http.Request)
+	GetCategories(w http.ResponseWriter, r *http.Request)
+	SearchPosts(w http.ResponseWriter, r *http.Request)
+	EditPost(w http.ResponseWriter, r *http.Request)
 	UpvotePost(w http.ResponseWriter, r *http.Request)
 	DownvotePost(w http.ResponseWriter, r *http.Request)
+	RetractPostVote(w http.ResponseWriter, r *http.Request)
 	ReportPost(w http.ResponseWriter, r *http.Request)
-	
+	DeleteOwnPost(w http.ResponseWriter, r *http.Request)
+
 	// Comment handlers
 	CreateComment(w http.ResponseWriter, r *http.Request)
 	GetComments(w http.ResponseWriter, r *http.Request)
-	
+	DeleteComment(w http.ResponseWriter, r *http.Request)
+	UpvoteComment(w http.ResponseWriter, r *http.Request)
+	DownvoteComment(w http.ResponseWriter, r *http.Request)
+
 	// Admin handlers
 	GetFlaggedPosts(w http.ResponseWriter, r *http.Request)
 	DeletePost(w http.ResponseWriter, r *http.Request)
+	GetPostRevisions(w http.ResponseWriter, r *http.Request)
+	GetPostForModeration(w http.ResponseWriter, r *http.Request)
+	GetCommentsForModeration(w http.ResponseWriter, r *http.Request)
 	BanUser(w http.ResponseWriter, r *http.Request)
+	SetUserRole(w http.ResponseWriter, r *http.Request)
+	RevokeUserRole(w http.ResponseWriter, r *http.Request)
+	ClearLoginLockout(w http.ResponseWriter, r *http.Request)
 }
 
 func New(s services.Service, v *validator.Validate) HandlerV1 {
+	// Report validation errors by JSON field name
+	v.RegisterTagNameFunc(response.JSONTagName)
+
 	return &handlerV1{Service: s, Validate: v}
 }
+
+// invalidQuery reports a malformed query parameter
+func invalidQuery(param string) error {
+	return entities.NewValidationError("invalid "+param+" query parameter", map[string]string{param: "is invalid"})
+}
+
+// decodeOptionalBody decodes a JSON body into v, treating an empty body as valid
+func decodeOptionalBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return response.InvalidBody(err)
+	}
+	return nil
+}
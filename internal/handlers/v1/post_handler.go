@@ -2,7 +2,11 @@ package v1
 
 import (
 	"encoding/json"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/handlers/response"
 	"hyperlocal/internal/services"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 
@@ -10,63 +14,163 @@ import (
 	"github.com/google/uuid"
 )
 
+// maxPostUploadBytes caps a multipart post creation body. Per-image limits
+// are enforced by the service.
+const maxPostUploadBytes = 32 << 20
+
 // CreatePost handles post creation
 // @Summary Create a new post
-// @Description Create a new post with content and location
+// @Description Create a new post with content and location. Images can be attached by sending multipart/form-data with the same fields and one or more "images" files.
 // @Tags posts
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Security BearerAuth
 // @Param request body services.CreatePostRequest true "Post details"
 // @Success 201 {object} services.PostResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 429 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts [post]
 func (h *handlerV1) CreatePost(w http.ResponseWriter, r *http.Request) {
 	var req services.CreatePostRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		r.Body = http.MaxBytesReader(w, r.Body, maxPostUploadBytes)
+		if err := r.ParseMultipartForm(maxPostUploadBytes); err != nil {
+			response.Error(w, r, response.InvalidBody(err))
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		closeImages, err := decodePostForm(r.MultipartForm, &req)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		defer closeImages()
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.InvalidBody(err))
 		return
 	}
 
 	if err := h.Validate.Struct(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.Validation(err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID := r.Context().Value("userID")
 	if userID == nil {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		response.Error(w, r, entities.ErrAuthRequired)
 		return
 	}
 
 	post, err := h.Service.CreatePost(req, userID.(uuid.UUID))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(post)
+	response.JSON(w, http.StatusCreated, post)
+}
+
+// decodePostForm fills req from the fields and "images" files of a multipart
+// form. The returned function closes the opened files.
+func decodePostForm(form *multipart.Form, req *services.CreatePostRequest) (func(), error) {
+	value := func(key string) string {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	req.Content = value("content")
+	req.Category = value("category")
+	req.Language = value("language")
+	req.LocationPrecision = value("location_precision")
+
+	details := map[string]string{}
+	if raw := value("anonymous"); raw != "" {
+		anonymous, err := strconv.ParseBool(raw)
+		if err != nil {
+			details["anonymous"] = "must be a boolean"
+		}
+		req.Anonymous = anonymous
+	}
+
+	if raw := value("expires_in"); raw != "" {
+		expiresIn, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			details["expires_in"] = "must be a number of seconds"
+		}
+		req.ExpiresIn = &expiresIn
+	}
+
+	for key, field := range map[string]*float64{"latitude": &req.Latitude, "longitude": &req.Longitude} {
+		if raw := value(key); raw != "" {
+			parsed, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				details[key] = "must be a number"
+				continue
+			}
+			*field = parsed
+		}
+	}
+	if len(details) > 0 {
+		return nil, entities.NewValidationError("invalid request body", details)
+	}
+
+	var files []multipart.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	for _, header := range form.File["images"] {
+		f, err := header.Open()
+		if err != nil {
+			closeFiles()
+			return nil, err
+		}
+		files = append(files, f)
+		req.Images = append(req.Images, services.ImageUpload{Filename: header.Filename, Reader: f})
+	}
+
+	return closeFiles, nil
+}
+
+// GetCategories handles listing the post categories
+// @Summary List post categories
+// @Description Get the curated categories a post can be filed under
+// @Tags posts
+// @Produce json
+// @Success 200 {array} services.CategoryResponse
+// @Router /categories [get]
+func (h *handlerV1) GetCategories(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, h.Service.GetCategories())
 }
 
 // GetNearbyPosts handles retrieving posts near a location
 // @Summary Get nearby posts
-// @Description Get posts within 5km of the specified location
+// @Description Get a page of posts within a radius of the specified location, ranked by the chosen sort mode
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param lat query number true "Latitude"
 // @Param lng query number true "Longitude"
-// @Success 200 {array} services.PostResponse
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Param radius query number false "Radius in metres (default 5000, clamped to 100-50000)"
+// @Param sort query string false "Ranking: new (default), top or hot" Enums(new, top, hot)
+// @Param window query string false "Time window for sort=top (default week)" Enums(day, week, month, all)
+// @Param category query string false "Only posts in this category, one of those listed by GET /categories"
+// @Param tag query string false "Only posts with this hashtag, with or without the leading #"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} services.FeedResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts [get]
 func (h *handlerV1) GetNearbyPosts(w http.ResponseWriter, r *http.Request) {
 	// Get latitude and longitude from query parameters
@@ -74,30 +178,65 @@ func (h *handlerV1) GetNearbyPosts(w http.ResponseWriter, r *http.Request) {
 	lngStr := r.URL.Query().Get("lng")
 
 	if latStr == "" || lngStr == "" {
-		http.Error(w, "Latitude and longitude are required", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("latitude and longitude are required", map[string]string{"lat": "is required", "lng": "is required"}))
 		return
 	}
 
 	lat, err := strconv.ParseFloat(latStr, 64)
 	if err != nil {
-		http.Error(w, "Invalid latitude", http.StatusBadRequest)
+		response.Error(w, r, invalidQuery("lat"))
 		return
 	}
 
 	lng, err := strconv.ParseFloat(lngStr, 64)
 	if err != nil {
-		http.Error(w, "Invalid longitude", http.StatusBadRequest)
+		response.Error(w, r, invalidQuery("lng"))
 		return
 	}
 
-	posts, err := h.Service.GetNearbyPosts(lat, lng)
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	query := services.FeedQuery{
+		UserID:    userID.(uuid.UUID),
+		Latitude:  lat,
+		Longitude: lng,
+		Sort:      r.URL.Query().Get("sort"),
+		Window:    r.URL.Query().Get("window"),
+		Category:  r.URL.Query().Get("category"),
+		Tag:       r.URL.Query().Get("tag"),
+		Cursor:    r.URL.Query().Get("cursor"),
+	}
+
+	if radiusStr := r.URL.Query().Get("radius"); radiusStr != "" {
+		radius, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radius <= 0 {
+			response.Error(w, r, invalidQuery("radius"))
+			return
+		}
+		query.RadiusMeters = radius
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			response.Error(w, r, invalidQuery("limit"))
+			return
+		}
+		query.Limit = limit
+	}
+
+	feed, err := h.Service.GetNearbyPosts(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	response.JSON(w, http.StatusOK, feed)
 }
 
 // UpvotePost handles upvoting a post
@@ -108,36 +247,35 @@ func (h *handlerV1) GetNearbyPosts(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Post ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts/{id}/upvote [post]
 func (h *handlerV1) UpvotePost(w http.ResponseWriter, r *http.Request) {
 	// Get post ID from URL
 	postIDStr := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
 	// Get user ID from context
 	userID := r.Context().Value("userID")
 	if userID == nil {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		response.Error(w, r, entities.ErrAuthRequired)
 		return
 	}
 
 	if err := h.Service.UpvotePost(postID, userID.(uuid.UUID)); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Post upvoted successfully"})
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Post upvoted successfully"})
 }
 
 // DownvotePost handles downvoting a post
@@ -148,36 +286,73 @@ func (h *handlerV1) UpvotePost(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Post ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts/{id}/downvote [post]
 func (h *handlerV1) DownvotePost(w http.ResponseWriter, r *http.Request) {
 	// Get post ID from URL
 	postIDStr := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
 	// Get user ID from context
 	userID := r.Context().Value("userID")
 	if userID == nil {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		response.Error(w, r, entities.ErrAuthRequired)
 		return
 	}
 
 	if err := h.Service.DownvotePost(postID, userID.(uuid.UUID)); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Post downvoted successfully"})
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Post downvoted successfully"})
+}
+
+// RetractPostVote handles removing the caller's vote on a post
+// @Summary Retract a vote
+// @Description Remove your upvote or downvote on a post
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/{id}/vote [delete]
+func (h *handlerV1) RetractPostVote(w http.ResponseWriter, r *http.Request) {
+	// Get post ID from URL
+	postIDStr := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.RetractPostVote(postID, userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Vote removed successfully"})
 }
 
 // ReportPost handles reporting a post
@@ -189,21 +364,21 @@ func (h *handlerV1) DownvotePost(w http.ResponseWriter, r *http.Request) {
 // @Security BearerAuth
 // @Param id path string true "Post ID"
 // @Param request body services.ReportPostRequest true "Report details"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /posts/{id}/report [post]
 func (h *handlerV1) ReportPost(w http.ResponseWriter, r *http.Request) {
 	var req services.ReportPostRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.InvalidBody(err))
 		return
 	}
 
 	if err := h.Validate.Struct(req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		response.Error(w, r, response.Validation(err))
 		return
 	}
 
@@ -211,55 +386,241 @@ func (h *handlerV1) ReportPost(w http.ResponseWriter, r *http.Request) {
 	postIDStr := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
 	// Get user ID from context
 	userID := r.Context().Value("userID")
 	if userID == nil {
-		http.Error(w, "User not authenticated", http.StatusUnauthorized)
+		response.Error(w, r, entities.ErrAuthRequired)
 		return
 	}
 
 	if err := h.Service.ReportPost(req, postID, userID.(uuid.UUID)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Post reported successfully"})
+}
+
+// EditPost handles editing a post
+// @Summary Edit a post
+// @Description Replace the content of your own post within the edit window. The previous content is kept as a revision.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Param request body services.EditPostRequest true "New content"
+// @Success 200 {object} services.PostResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/{id} [patch]
+func (h *handlerV1) EditPost(w http.ResponseWriter, r *http.Request) {
+	// Get post ID from URL
+	postIDStr := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	var req services.EditPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, r, response.InvalidBody(err))
+		return
+	}
+
+	if err := h.Validate.Struct(req); err != nil {
+		response.Error(w, r, response.Validation(err))
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	post, err := h.Service.EditPost(req, postID, userID.(uuid.UUID))
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Post reported successfully"})
+	response.JSON(w, http.StatusOK, post)
 }
 
-// DeletePost handles deleting a post (admin only)
+// GetPostRevisions handles retrieving the edit history of a post (moderator only)
+// @Summary Get post revisions
+// @Description Get the earlier versions of a post, oldest first (moderator only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Success 200 {array} services.PostRevisionResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /admin/posts/{id}/revisions [get]
+func (h *handlerV1) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	// Get post ID from URL
+	postIDStr := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	revisions, err := h.Service.GetPostRevisions(postID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, revisions)
+}
+
+// DeleteOwnPost handles an author deleting their post
+// @Summary Delete your post
+// @Description Remove your own post from feeds. Moderators can still see it.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Param request body services.DeleteRequest false "Optional reason"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/{id} [delete]
+func (h *handlerV1) DeleteOwnPost(w http.ResponseWriter, r *http.Request) {
+	// Get post ID from URL
+	postIDStr := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	var req services.DeleteRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if err := h.Validate.Struct(req); err != nil {
+		response.Error(w, r, response.Validation(err))
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.DeleteOwnPost(req, postID, userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Post deleted successfully"})
+}
+
+// DeletePost handles deleting a post (moderator only)
 // @Summary Delete a post
-// @Description Delete a post by ID (admin only)
+// @Description Remove a post by ID from feeds, recording the moderator and reason (moderator only)
 // @Tags admin
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Post ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 403 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Param request body services.DeleteRequest false "Optional reason"
+// @Success 200 {object} response.MessageResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
 // @Router /admin/posts/{id} [delete]
 func (h *handlerV1) DeletePost(w http.ResponseWriter, r *http.Request) {
 	// Get post ID from URL
 	postIDStr := chi.URLParam(r, "id")
 	postID, err := uuid.Parse(postIDStr)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
+		return
+	}
+
+	var req services.DeleteRequest
+	if err := decodeOptionalBody(r, &req); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if err := h.Validate.Struct(req); err != nil {
+		response.Error(w, r, response.Validation(err))
+		return
+	}
+
+	// Get moderator ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	if err := h.Service.DeletePost(req, postID, userID.(uuid.UUID)); err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, response.MessageResponse{Message: "Post deleted successfully"})
+}
+
+// GetPostForModeration handles retrieving a post for review (moderator only)
+// @Summary Get a post for moderation
+// @Description Get a post by ID, including a removed post and who removed it (moderator only)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Post ID"
+// @Success 200 {object} services.PostResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /admin/posts/{id} [get]
+func (h *handlerV1) GetPostForModeration(w http.ResponseWriter, r *http.Request) {
+	// Get post ID from URL
+	postIDStr := chi.URLParam(r, "id")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		response.Error(w, r, entities.NewValidationError("invalid post ID", map[string]string{"id": "must be a UUID"}))
 		return
 	}
 
-	if err := h.Service.DeletePost(postID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	post, err := h.Service.GetPostByID(postID)
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Post deleted successfully"})
-}
\ No newline at end of file
+	response.JSON(w, http.StatusOK, post)
+}
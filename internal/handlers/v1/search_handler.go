@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/handlers/response"
+	"hyperlocal/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// SearchPosts handles full-text search over nearby posts and their comments
+// @Summary Search nearby posts
+// @Description Search posts and comments within a radius of a location. Results are ranked by text relevance, distance and recency, and carry an HTML-escaped snippet with matches wrapped in <mark>.
+// @Tags posts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search query; supports quoted phrases, OR and -exclusions"
+// @Param lat query number true "Latitude"
+// @Param lng query number true "Longitude"
+// @Param radius query number false "Radius in metres (default 5000, clamped to 100-50000)"
+// @Param lang query string false "Only search posts written in this language, a Postgres text search configuration such as simple or english (default all)"
+// @Param limit query int false "Page size (default 20, max 50)"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Success 200 {object} services.SearchResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /posts/search [get]
+func (h *handlerV1) SearchPosts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	if params.Get("q") == "" {
+		response.Error(w, r, entities.NewValidationError("search query is required", map[string]string{"q": "is required"}))
+		return
+	}
+
+	latStr := params.Get("lat")
+	lngStr := params.Get("lng")
+	if latStr == "" || lngStr == "" {
+		response.Error(w, r, entities.NewValidationError("latitude and longitude are required", map[string]string{"lat": "is required", "lng": "is required"}))
+		return
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		response.Error(w, r, invalidQuery("lat"))
+		return
+	}
+
+	lng, err := strconv.ParseFloat(lngStr, 64)
+	if err != nil {
+		response.Error(w, r, invalidQuery("lng"))
+		return
+	}
+
+	// Get user ID from context
+	userID := r.Context().Value("userID")
+	if userID == nil {
+		response.Error(w, r, entities.ErrAuthRequired)
+		return
+	}
+
+	query := services.SearchQuery{
+		UserID:    userID.(uuid.UUID),
+		Query:     params.Get("q"),
+		Latitude:  lat,
+		Longitude: lng,
+		Language:  params.Get("lang"),
+		Cursor:    params.Get("cursor"),
+	}
+
+	if radiusStr := params.Get("radius"); radiusStr != "" {
+		radius, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil || radius <= 0 {
+			response.Error(w, r, invalidQuery("radius"))
+			return
+		}
+		query.RadiusMeters = radius
+	}
+
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			response.Error(w, r, invalidQuery("limit"))
+			return
+		}
+		query.Limit = limit
+	}
+
+	results, err := h.Service.SearchPosts(query)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, results)
+}
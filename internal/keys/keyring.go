@@ -0,0 +1,257 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is one JWT signing key
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey interface{}
+	PublicKey  interface{}
+	// ActiveFrom is when the key starts signing tokens. It stays valid for
+	// verification until the next key has been active for the grace period.
+	ActiveFrom time.Time
+}
+
+// KeyRing holds the signing keys ordered by activation time
+type KeyRing struct {
+	keys        []Key
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+// manifestEntry is one key in the JWT_KEYS_FILE manifest
+type manifestEntry struct {
+	ID             string    `json:"kid"`
+	PrivateKeyFile string    `json:"private_key_file"`
+	ActiveFrom     time.Time `json:"active_from"`
+}
+
+var (
+	ErrNoSigningKey = errors.New("no active signing key")
+	ErrUnknownKey   = errors.New("unknown or retired signing key")
+)
+
+// NewKeyRing creates a key ring from the given keys
+func NewKeyRing(keys []Key, gracePeriod time.Duration) *KeyRing {
+	sorted := append([]Key(nil), keys...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ActiveFrom.Before(sorted[j].ActiveFrom)
+	})
+
+	return &KeyRing{keys: sorted, gracePeriod: gracePeriod, now: time.Now}
+}
+
+// LoadFromEnv builds the key ring from the environment.
+//
+// JWT_KEYS_FILE points to a JSON manifest listing RS256 or EdDSA keys:
+//
+//	[{"kid": "2026-10", "private_key_file": "2026-10.pem", "active_from": "2026-10-01T00:00:00Z"}]
+//
+// Key files hold PEM encoded PKCS#8 (or PKCS#1 RSA) private keys; relative
+// paths are resolved against the manifest's directory. Listing a key with a
+// future active_from schedules a rotation: it is published in the JWKS right
+// away and takes over signing at that time. JWT_KEY_GRACE_PERIOD (default 1h)
+// is how long a replaced key keeps verifying tokens.
+//
+// Without JWT_KEYS_FILE the ring falls back to a single HS256 key from JWT_SECRET.
+func LoadFromEnv() (*KeyRing, error) {
+	gracePeriod := time.Hour
+	if value := os.Getenv("JWT_KEY_GRACE_PERIOD"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_KEY_GRACE_PERIOD: %w", err)
+		}
+		gracePeriod = d
+	}
+
+	manifestPath := os.Getenv("JWT_KEYS_FILE")
+	if manifestPath == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_KEYS_FILE or JWT_SECRET must be set")
+		}
+		return NewKeyRing([]Key{{
+			Method:     jwt.SigningMethodHS256,
+			PrivateKey: []byte(secret),
+			PublicKey:  []byte(secret),
+		}}, gracePeriod), nil
+	}
+
+	raw, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []manifestEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("invalid key manifest: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("key manifest is empty")
+	}
+
+	keys := make([]Key, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.ID == "" {
+			return nil, errors.New("every key in the manifest needs a kid")
+		}
+		if seen[entry.ID] {
+			return nil, fmt.Errorf("duplicate kid %q in key manifest", entry.ID)
+		}
+		seen[entry.ID] = true
+
+		path := entry.PrivateKeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(manifestPath), path)
+		}
+
+		key, err := loadPrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", entry.ID, err)
+		}
+		key.ID = entry.ID
+		key.ActiveFrom = entry.ActiveFrom
+		keys = append(keys, *key)
+	}
+
+	return NewKeyRing(keys, gracePeriod), nil
+}
+
+// loadPrivateKey reads a PEM private key and picks the matching signing method
+func loadPrivateKey(path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{Method: jwt.SigningMethodRS256, PrivateKey: private, PublicKey: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{Method: jwt.SigningMethodEdDSA, PrivateKey: private, PublicKey: private.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+}
+
+// signingKey returns the most recently activated key
+func (k *KeyRing) signingKey() (*Key, error) {
+	now := k.now()
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if !k.keys[i].ActiveFrom.After(now) {
+			return &k.keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// retiresAt returns when the key at index i stops verifying tokens, or the
+// zero time if no newer key has replaced it yet
+func (k *KeyRing) retiresAt(i int) time.Time {
+	now := k.now()
+	for j := i + 1; j < len(k.keys); j++ {
+		if !k.keys[j].ActiveFrom.After(now) {
+			return k.keys[j].ActiveFrom.Add(k.gracePeriod)
+		}
+	}
+	return time.Time{}
+}
+
+// retired reports whether the key at index i is past its grace period
+func (k *KeyRing) retired(i int) bool {
+	retiresAt := k.retiresAt(i)
+	return !retiresAt.IsZero() && k.now().After(retiresAt)
+}
+
+// Sign signs the claims with the current signing key and sets its kid header
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	key, err := k.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.PrivateKey)
+}
+
+// Keyfunc resolves the verification key for a token from its kid header,
+// for use with jwt.Parse
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	now := k.now()
+
+	for i, key := range k.keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.ActiveFrom.After(now) || k.retired(i) {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.PublicKey, nil
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// Methods lists the algorithms of the keys in the ring
+func (k *KeyRing) Methods() []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, key := range k.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// publicKeys returns the keys that should currently be published: those that
+// are scheduled, signing, or still in their grace period
+func (k *KeyRing) publicKeys() []Key {
+	var published []Key
+	for i, key := range k.keys {
+		if !k.retired(i) {
+			published = append(published, key)
+		}
+	}
+	return published
+}
@@ -1,39 +1,94 @@
 package models
 
 import (
+	"errors"
 	"hyperlocal/internal/entities"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// CreateComment creates a new comment on a post
-func (m *Model) CreateComment(postID, userID uuid.UUID, content string) (*entities.Comment, error) {
-	comment := &entities.Comment{
-		ID:        uuid.New(),
-		PostID:    postID,
-		UserID:    userID,
-		Content:   content,
-		CreatedAt: time.Now(),
-	}
+// CreateComment creates a new comment on a post. The caller fills in the
+// post, author, content, language and, for replies, the parent and depth; the
+// ID, creation time and, for anonymous comments, the author's pseudonym are
+// set here. Replies bump the parent's reply count.
+func (m *Model) CreateComment(comment *entities.Comment) error {
+	comment.ID = uuid.New()
+	comment.CreatedAt = time.Now()
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if comment.IsAnonymous {
+			pseudonym, err := assignPseudonym(tx, comment.PostID, comment.UserID)
+			if err != nil {
+				return err
+			}
+			comment.Pseudonym = pseudonym
+		}
+
+		if err := tx.Create(comment).Error; err != nil {
+			if errors.Is(err, gorm.ErrForeignKeyViolated) {
+				return entities.ErrPostNotFound
+			}
+			return err
+		}
+
+		if comment.ParentID == nil {
+			return nil
+		}
+		return tx.Model(&entities.Comment{}).Where("id = ?", *comment.ParentID).Update("reply_count", gorm.Expr("reply_count + 1")).Error
+	})
+}
 
-	if err := m.db.Create(comment).Error; err != nil {
+// GetCommentByID retrieves a comment by ID, including a soft-deleted one
+func (m *Model) GetCommentByID(id uuid.UUID) (*entities.Comment, error) {
+	var comment entities.Comment
+	if err := m.db.Preload("User").First(&comment, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrCommentNotFound
+		}
 		return nil, err
 	}
-
-	return comment, nil
+	return &comment, nil
 }
 
-// GetCommentsByPostID retrieves all comments for a post
+// GetCommentsByPostID retrieves every comment for a post, including
+// soft-deleted ones, oldest first
 func (m *Model) GetCommentsByPostID(postID uuid.UUID) ([]entities.Comment, error) {
 	var comments []entities.Comment
-	if err := m.db.Where("post_id = ?", postID).Preload("User").Order("created_at DESC").Find(&comments).Error; err != nil {
+	if err := m.db.Where("post_id = ?", postID).Preload("User").Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
 		return nil, err
 	}
 	return comments, nil
 }
 
-// DeleteComment deletes a comment
-func (m *Model) DeleteComment(id uuid.UUID) error {
-	return m.db.Delete(&entities.Comment{}, "id = ?", id).Error
+// DeleteComment soft-deletes a comment, recording who removed it and why. The
+// parent's reply count only counts replies that have not been deleted.
+func (m *Model) DeleteComment(id, deletedBy uuid.UUID, reason string) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		var comment entities.Comment
+		if err := tx.First(&comment, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return entities.ErrCommentNotFound
+			}
+			return err
+		}
+
+		result := tx.Model(&entities.Comment{}).Where("id = ? AND deleted_at IS NULL", id).Updates(map[string]interface{}{
+			"deleted_at":      time.Now(),
+			"deleted_by":      deletedBy,
+			"deletion_reason": reason,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entities.ErrCommentNotFound
+		}
+
+		if comment.ParentID == nil {
+			return nil
+		}
+		return tx.Model(&entities.Comment{}).Where("id = ?", *comment.ParentID).Update("reply_count", gorm.Expr("GREATEST(reply_count - 1, 0)")).Error
+	})
 }
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"hyperlocal/internal/entities"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetLoginThrottle retrieves the failed login state of a key. A key without
+// failures returns an empty throttle.
+func (m *Model) GetLoginThrottle(key string) (*entities.LoginThrottle, error) {
+	var throttle entities.LoginThrottle
+	if err := m.db.First(&throttle, "key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &entities.LoginThrottle{Key: key}, nil
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// RecordLoginFailure atomically counts a failed login at now for a key. The count
+// starts over when the previous failure is older than resetAfter, and the key
+// is locked for lockout once it reaches lockAfter failures.
+func (m *Model) RecordLoginFailure(key string, now time.Time, lockAfter int, lockout, resetAfter time.Duration) (*entities.LoginThrottle, error) {
+	var throttle entities.LoginThrottle
+	err := m.db.Raw(`
+		INSERT INTO login_throttles (key, failures, last_failure_at, locked_until)
+		VALUES (@key, 1, @now, CASE WHEN @lock_after <= 1 THEN @locked_until END)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < @reset_before THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = @now,
+			locked_until = CASE
+				WHEN login_throttles.last_failure_at >= @reset_before
+					AND login_throttles.failures + 1 >= @lock_after THEN @locked_until
+				ELSE login_throttles.locked_until
+			END
+		RETURNING *
+	`, map[string]interface{}{
+		"key":          key,
+		"now":          now,
+		"lock_after":   lockAfter,
+		"locked_until": now.Add(lockout),
+		"reset_before": now.Add(-resetAfter),
+	}).Scan(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttle, nil
+}
+
+// ClearLoginThrottle forgets the failed logins of the given keys
+func (m *Model) ClearLoginThrottle(keys ...string) error {
+	return m.db.Where("key IN ?", keys).Delete(&entities.LoginThrottle{}).Error
+}
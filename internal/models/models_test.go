@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"hyperlocal/internal/db/postgres"
+	"hyperlocal/internal/entities"
+
+	"github.com/google/uuid"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testModel connects to the database in TEST_DATABASE_URL and migrates it,
+// skipping the test when no test database is configured. Tests create their
+// own rows and must not rely on the database being empty.
+func testModel(t *testing.T) (*Model, *gorm.DB) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	t.Setenv("DATABASE_URL", url)
+
+	db := postgres.Connect()
+	db = db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := postgres.MigrateUp(db); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	return New(db), db
+}
+
+// createTestUser creates a user with a unique username
+func createTestUser(t *testing.T, m *Model) *entities.User {
+	t.Helper()
+
+	username := fmt.Sprintf("test_%s", uuid.NewString()[:8])
+	user, err := m.CreateUser(&username, "password")
+	if err != nil {
+		t.Fatalf("create test user: %v", err)
+	}
+	return user
+}
@@ -1,69 +1,297 @@
 package models
 
 import (
+	"errors"
 	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// CreatePost creates a new post
-func (m *Model) CreatePost(userID uuid.UUID, content string, latitude, longitude float64) (*entities.Post, error) {
-	post := &entities.Post{
-		ID:        uuid.New(),
-		UserID:    userID,
-		Content:   content,
-		Latitude:  latitude,
-		Longitude: longitude,
-		CreatedAt: time.Now(),
+// CreatePost creates a new post. The caller fills in the author, content,
+// category, tags and coordinates; the ID, location and creation time are set here.
+// Any images are inserted with the post in the same transaction, and an
+// anonymous author becomes the post's first participant.
+func (m *Model) CreatePost(post *entities.Post) error {
+	post.ID = uuid.New()
+	post.Location = entities.GeoPoint{Longitude: post.Longitude, Latitude: post.Latitude}
+	post.CreatedAt = time.Now()
+
+	if !post.IsAnonymous {
+		return m.db.Create(post).Error
 	}
 
-	if err := m.db.Create(post).Error; err != nil {
-		return nil, err
+	post.Pseudonym = 1
+	post.ParticipantCount = 1
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entities.PostParticipant{PostID: post.ID, UserID: post.UserID, Pseudonym: 1}).Error
+	})
+}
+
+// assignPseudonym returns the pseudonym of a user on a post, handing out the
+// next number the first time they write there anonymously
+func assignPseudonym(tx *gorm.DB, postID, userID uuid.UUID) (int, error) {
+	// Lock the post so concurrent participants get distinct numbers and a
+	// user racing with themselves still gets a single one
+	var post entities.Post
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "participant_count").First(&post, "id = ?", postID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, entities.ErrPostNotFound
+		}
+		return 0, err
 	}
 
-	return post, nil
+	var participant entities.PostParticipant
+	err := tx.Where("post_id = ? AND user_id = ?", postID, userID).Take(&participant).Error
+	if err == nil {
+		return participant.Pseudonym, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	participant = entities.PostParticipant{PostID: postID, UserID: userID, Pseudonym: post.ParticipantCount + 1}
+	if err := tx.Create(&participant).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&entities.Post{}).Where("id = ?", postID).Update("participant_count", participant.Pseudonym).Error; err != nil {
+		return 0, err
+	}
+	return participant.Pseudonym, nil
 }
 
-// GetPostByID retrieves a post by ID
+// GetPostByID retrieves a post by ID, including a soft-deleted one
 func (m *Model) GetPostByID(id uuid.UUID) (*entities.Post, error) {
 	var post entities.Post
-	if err := m.db.Preload("User").First(&post, "id = ?", id).Error; err != nil {
+	if err := m.db.Preload("User").Preload("Images", orderImages).First(&post, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, entities.ErrPostNotFound
+		}
 		return nil, err
 	}
 	return &post, nil
 }
 
-// GetNearbyPosts retrieves posts within a specified radius of a location
-func (m *Model) GetNearbyPosts(latitude, longitude float64, radiusMeters float64) ([]entities.Post, error) {
+// FeedSort selects how the nearby feed is ranked
+type FeedSort string
+
+const (
+	// SortNew orders posts by creation time
+	SortNew FeedSort = "new"
+	// SortTop orders posts by net votes
+	SortTop FeedSort = "top"
+	// SortHot orders posts by a time-decayed engagement score
+	SortHot FeedSort = "hot"
+)
+
+// PostCursor marks a position in the nearby feed, which is ordered by
+// score DESC, created_at DESC, id DESC
+type PostCursor struct {
+	Score     float64
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// NearbyPostsFilter describes a page of the nearby feed
+type NearbyPostsFilter struct {
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64
+	Sort         FeedSort
+	// Since restricts the feed to posts created after it, used by SortTop windows
+	Since *time.Time
+	// Category and Tag restrict the feed when set
+	Category enums.Category
+	Tag      string
+	Limit    int
+	After    *PostCursor
+}
+
+// feedScoreExpr returns the SQL expression ranking posts for a sort mode.
+// It may reference distance_meters and comment_count from the inner query.
+//
+// The hot score follows the usual log-scaled engagement plus creation time
+// formula: every 12.5 hours of age are worth one order of magnitude of
+// engagement, and a post at the edge of the radius loses one more unit.
+// Because it is anchored to the epoch rather than to now(), the score of a
+// post only changes when it is voted or commented on, so keyset pagination
+// over it stays stable.
+func feedScoreExpr(sort FeedSort) string {
+	switch sort {
+	case SortTop:
+		return `(upvotes - downvotes)::float8`
+	case SortHot:
+		return `(
+			SIGN(upvotes - downvotes + 0.5 * comment_count)
+				* LOG(GREATEST(ABS(upvotes - downvotes + 0.5 * comment_count), 1))
+			+ EXTRACT(EPOCH FROM created_at) / 45000
+			- distance_meters / @radius
+		)::float8`
+	default:
+		return `0::float8`
+	}
+}
+
+// GetNearbyPosts retrieves a page of posts within a specified radius of a location
+func (m *Model) GetNearbyPosts(filter NearbyPostsFilter) ([]entities.Post, error) {
 	var posts []entities.Post
 
+	query, args := nearbyPostsQuery(filter)
+	if err := m.db.Raw(query, args).Scan(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	if err := m.loadPostUsers(posts); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadPostImages(posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// nearbyPostsQuery builds the SQL and named arguments of a nearby feed page
+func nearbyPostsQuery(filter NearbyPostsFilter) (string, map[string]interface{}) {
 	// Using PostGIS ST_DWithin to find posts within radius
-	// ST_MakePoint creates a point from longitude and latitude (note the order)
+	// location is the stored geography point, covered by the idx_posts_location GiST index
+	// ST_MakePoint creates the caller's point from longitude and latitude (note the order)
 	// ST_SetSRID sets the spatial reference system identifier (SRID) to 4326 (WGS84)
 	// ST_DWithin checks if the distance between two geometries is within a given value
+	// ST_Distance returns the distance in metres between the post and the caller
+	nearby := `
+		SELECT posts.*,
+			ST_Distance(location, ST_SetSRID(ST_MakePoint(@lng, @lat), 4326)::geography) AS distance_meters,
+			(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL) AS comment_count
+		FROM posts 
+		WHERE ST_DWithin(location, ST_SetSRID(ST_MakePoint(@lng, @lat), 4326)::geography, @radius)
+			AND posts.deleted_at IS NULL
+			AND (posts.expires_at IS NULL OR posts.expires_at > now())
+	`
+	args := map[string]interface{}{
+		"lng":    filter.Longitude,
+		"lat":    filter.Latitude,
+		"radius": filter.RadiusMeters,
+		"limit":  filter.Limit,
+	}
+
+	if filter.Since != nil {
+		nearby += ` AND created_at >= @since`
+		args["since"] = *filter.Since
+	}
+
+	if filter.Category != "" {
+		nearby += ` AND category = @category`
+		args["category"] = filter.Category
+	}
+
+	// @> on the tags array is covered by the idx_posts_tags GIN index
+	if filter.Tag != "" {
+		nearby += ` AND tags @> ARRAY[@tag]::text[]`
+		args["tag"] = filter.Tag
+	}
+
 	query := `
-		SELECT * FROM posts 
-		WHERE ST_DWithin(
-			ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography,
-			ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography,
-			?
-		)
-		ORDER BY created_at DESC
+		SELECT * FROM (
+			SELECT nearby.*, ` + feedScoreExpr(filter.Sort) + ` AS score
+			FROM (` + nearby + `) nearby
+		) ranked
 	`
 
-	if err := m.db.Raw(query, longitude, latitude, radiusMeters).Scan(&posts).Error; err != nil {
-		return nil, err
+	// Keyset pagination: continue strictly after the last row of the previous page
+	if filter.After != nil {
+		query += ` WHERE (score, created_at, id) < (@after_score, @after_created_at, @after_id)`
+		args["after_score"] = filter.After.Score
+		args["after_created_at"] = filter.After.CreatedAt
+		args["after_id"] = filter.After.ID
+	}
+
+	query += `
+		ORDER BY score DESC, created_at DESC, id DESC
+		LIMIT @limit
+	`
+
+	return query, args
+}
+
+// loadPostUsers loads the author of every post in one query
+func (m *Model) loadPostUsers(posts []entities.Post) error {
+	if len(posts) == 0 {
+		return nil
 	}
 
-	// Load user data for each post
+	userIDs := make([]uuid.UUID, len(posts))
 	for i := range posts {
-		if err := m.db.Model(&posts[i]).Association("User").Find(&posts[i].User); err != nil {
-			return nil, err
-		}
+		userIDs[i] = posts[i].UserID
 	}
 
-	return posts, nil
+	var users []entities.User
+	if err := m.db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uuid.UUID]entities.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for i := range posts {
+		posts[i].User = byID[posts[i].UserID]
+	}
+	return nil
+}
+
+// loadPostImages loads the images of every post in one query
+func (m *Model) loadPostImages(posts []entities.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]uuid.UUID, len(posts))
+	for i := range posts {
+		postIDs[i] = posts[i].ID
+	}
+
+	var images []entities.PostImage
+	if err := orderImages(m.db.Where("post_id IN ?", postIDs)).Find(&images).Error; err != nil {
+		return err
+	}
+
+	byPost := make(map[uuid.UUID][]entities.PostImage, len(posts))
+	for _, image := range images {
+		byPost[image.PostID] = append(byPost[image.PostID], image)
+	}
+	for i := range posts {
+		posts[i].Images = byPost[posts[i].ID]
+	}
+	return nil
+}
+
+// orderImages orders post images as the author uploaded them
+func orderImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// IsImageVisible reports whether the image or thumbnail stored under key
+// belongs to a post that is neither deleted nor expired
+func (m *Model) IsImageVisible(key string) (bool, error) {
+	var visible bool
+	err := m.db.Raw(`
+		SELECT EXISTS (
+			SELECT 1 FROM post_images
+			JOIN posts ON posts.id = post_images.post_id
+			WHERE (post_images.key = @key OR post_images.thumbnail_key = @key)
+				AND posts.deleted_at IS NULL
+				AND (posts.expires_at IS NULL OR posts.expires_at > now())
+		)
+	`, map[string]interface{}{"key": key}).Scan(&visible).Error
+	return visible, err
 }
 
 // UpdatePost updates a post
@@ -71,9 +299,150 @@ func (m *Model) UpdatePost(post *entities.Post) error {
 	return m.db.Save(post).Error
 }
 
-// DeletePost deletes a post
-func (m *Model) DeletePost(id uuid.UUID) error {
-	return m.db.Delete(&entities.Post{}, "id = ?", id).Error
+// GetPostsWithPrecision returns up to limit posts stored at any of the given
+// precisions, loading only their location
+func (m *Model) GetPostsWithPrecision(precisions []enums.LocationPrecision, limit int) ([]entities.Post, error) {
+	var posts []entities.Post
+	err := m.db.Select("id", "latitude", "longitude", "location_precision").
+		Where("location_precision IN ?", precisions).
+		Order("id").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// UpdatePostLocation replaces the stored location of a post and the
+// precision it is stored at
+func (m *Model) UpdatePostLocation(id uuid.UUID, latitude, longitude float64, precision enums.LocationPrecision) error {
+	return m.db.Model(&entities.Post{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"latitude":           latitude,
+		"longitude":          longitude,
+		"location":           entities.GeoPoint{Longitude: longitude, Latitude: latitude},
+		"location_precision": precision,
+	}).Error
+}
+
+// EditPost replaces the content and tags of a post, keeping the previous
+// content as a revision. The post is updated in place.
+func (m *Model) EditPost(post *entities.Post, editorID uuid.UUID, content string, tags entities.Tags) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent edits each record the content they replaced
+		var current entities.Post
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", post.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return entities.ErrPostNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		revision := &entities.PostRevision{
+			ID:        uuid.New(),
+			PostID:    post.ID,
+			Content:   current.Content,
+			EditedBy:  editorID,
+			CreatedAt: now,
+		}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&entities.Post{}).Where("id = ?", post.ID).Updates(map[string]interface{}{
+			"content":   content,
+			"tags":      tags,
+			"edited_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		post.Content = content
+		post.Tags = tags
+		post.EditedAt = &now
+		return nil
+	})
+}
+
+// GetPostRevisions retrieves the earlier versions of a post, oldest first
+func (m *Model) GetPostRevisions(postID uuid.UUID) ([]entities.PostRevision, error) {
+	var revisions []entities.PostRevision
+	if err := m.db.Where("post_id = ?", postID).Order("created_at ASC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// DeletePost soft-deletes a post, recording who removed it and why. The row
+// and its images are kept so moderators can still review it; the images stop
+// being served publicly, see IsImageVisible.
+func (m *Model) DeletePost(id, deletedBy uuid.UUID, reason string) error {
+	result := m.db.Model(&entities.Post{}).Where("id = ? AND deleted_at IS NULL", id).Updates(map[string]interface{}{
+		"deleted_at":      time.Now(),
+		"deleted_by":      deletedBy,
+		"deletion_reason": reason,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrPostNotFound
+	}
+	return nil
+}
+
+// PurgeExpiredPosts permanently deletes up to limit posts that expired
+// before the cutoff, with their comments, votes and reports. Revisions,
+// images and pseudonyms go with the post through ON DELETE CASCADE. The
+// purged posts' images are returned so their blobs can be removed.
+func (m *Model) PurgeExpiredPosts(before time.Time, limit int) ([]entities.PostImage, int64, error) {
+	var images []entities.PostImage
+	var purged int64
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED lets several instances sweep at once without waiting on each other
+		var posts []entities.Post
+		err := tx.Raw(`
+			SELECT id FROM posts
+			WHERE expires_at < ?
+			ORDER BY expires_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, before, limit).Scan(&posts).Error
+		if err != nil || len(posts) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(posts))
+		for i := range posts {
+			ids[i] = posts[i].ID
+		}
+
+		if err := tx.Where("post_id IN ?", ids).Find(&images).Error; err != nil {
+			return err
+		}
+
+		// Children first, the foreign keys to posts and comments do not cascade
+		deletes := []string{
+			`DELETE FROM user_comment_votes WHERE comment_id IN (SELECT id FROM comments WHERE post_id IN ?)`,
+			`DELETE FROM comments WHERE post_id IN ?`,
+			`DELETE FROM user_post_votes WHERE post_id IN ?`,
+			`DELETE FROM reports WHERE post_id IN ?`,
+		}
+		for _, statement := range deletes {
+			if err := tx.Exec(statement, ids).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Exec(`DELETE FROM posts WHERE id IN ?`, ids)
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return images, purged, nil
 }
 
 // FlagPost marks a post as flagged
@@ -84,18 +453,8 @@ func (m *Model) FlagPost(id uuid.UUID) error {
 // GetFlaggedPosts retrieves all flagged posts
 func (m *Model) GetFlaggedPosts() ([]entities.Post, error) {
 	var posts []entities.Post
-	if err := m.db.Where("is_flagged = ?", true).Preload("User").Find(&posts).Error; err != nil {
+	if err := m.db.Where("is_flagged = ?", true).Preload("User").Preload("Images", orderImages).Find(&posts).Error; err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
-
-// UpvotePost increments the upvote count for a post
-func (m *Model) UpvotePost(postID uuid.UUID) error {
-	return m.db.Model(&entities.Post{}).Where("id = ?", postID).Update("upvotes", m.db.Raw("upvotes + 1")).Error
-}
-
-// DownvotePost increments the downvote count for a post
-func (m *Model) DownvotePost(postID uuid.UUID) error {
-	return m.db.Model(&entities.Post{}).Where("id = ?", postID).Update("downvotes", m.db.Raw("downvotes + 1")).Error
-}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestNearbyPostsUsesLocationIndex(t *testing.T) {
+	_, db := testModel(t)
+
+	query, args := nearbyPostsQuery(NearbyPostsFilter{
+		Latitude:     51.5072,
+		Longitude:    -0.1276,
+		RadiusMeters: 5000,
+		Sort:         SortNew,
+		Limit:        20,
+	})
+
+	var plan []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// A test database is too small for the planner to prefer an index on
+		// its own, so rule out sequential scans to see whether it can use one
+		if err := tx.Exec("SET LOCAL enable_seqscan = off").Error; err != nil {
+			return err
+		}
+		return tx.Raw("EXPLAIN "+query, args).Scan(&plan).Error
+	})
+	if err != nil {
+		t.Fatalf("explain nearby query: %v", err)
+	}
+
+	if !strings.Contains(strings.Join(plan, "\n"), "idx_posts_location") {
+		t.Fatalf("nearby query does not use idx_posts_location:\n%s", strings.Join(plan, "\n"))
+	}
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hyperlocal/internal/entities"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// hashRefreshToken returns the hex SHA-256 digest stored in place of a refresh token
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// StoreRefreshToken stores the hash of a refresh token for a user along with
+// its rotation family and the client that requested it
+func (m *Model) StoreRefreshToken(userID, familyID uuid.UUID, token string, expiresAt time.Time, userAgent, ipAddress string) (*entities.RefreshToken, error) {
+	refreshToken := &entities.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(token),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := m.db.Create(refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	return refreshToken, nil
+}
+
+// GetRefreshToken retrieves a refresh token, including already rotated ones
+func (m *Model) GetRefreshToken(token string) (*entities.RefreshToken, error) {
+	var refreshToken entities.RefreshToken
+	if err := m.db.Where("token_hash = ?", hashRefreshToken(token)).First(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// MarkRefreshTokenRotated marks a refresh token as used. It reports false if
+// the token had already been rotated, which means it is being replayed.
+func (m *Model) MarkRefreshTokenRotated(id uuid.UUID) (bool, error) {
+	result := m.db.Model(&entities.RefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteRefreshToken deletes a refresh token
+func (m *Model) DeleteRefreshToken(token string) error {
+	return m.db.Where("token_hash = ?", hashRefreshToken(token)).Delete(&entities.RefreshToken{}).Error
+}
+
+// DeleteRefreshTokenFamily deletes every token of a rotation family
+func (m *Model) DeleteRefreshTokenFamily(familyID uuid.UUID) error {
+	return m.db.Where("family_id = ?", familyID).Delete(&entities.RefreshToken{}).Error
+}
+
+// GetActiveRefreshTokens retrieves the current, unexpired refresh token of
+// each rotation family of a user, newest first, along with when the family
+// was started. Rotated tokens are kept, so the family's first token remains.
+func (m *Model) GetActiveRefreshTokens(userID uuid.UUID) ([]entities.RefreshToken, error) {
+	var refreshTokens []entities.RefreshToken
+	if err := m.db.Select("refresh_tokens.*, (SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = refresh_tokens.family_id) AS session_started_at").Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, time.Now()).Order("created_at DESC").Find(&refreshTokens).Error; err != nil {
+		return nil, err
+	}
+	return refreshTokens, nil
+}
+
+// DeleteUserRefreshTokenFamily deletes one rotation family belonging to a user
+func (m *Model) DeleteUserRefreshTokenFamily(userID, familyID uuid.UUID) error {
+	result := m.db.Where("family_id = ? AND user_id = ?", familyID, userID).Delete(&entities.RefreshToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserRefreshTokens deletes every refresh token of a user
+func (m *Model) DeleteUserRefreshTokens(userID uuid.UUID) error {
+	return m.db.Where("user_id = ?", userID).Delete(&entities.RefreshToken{}).Error
+}
+
+// DeleteExpiredRefreshTokens deletes all expired refresh tokens
+func (m *Model) DeleteExpiredRefreshTokens() error {
+	return m.db.Where("expires_at < ?", time.Now()).Delete(&entities.RefreshToken{}).Error
+}
+
+// RecordSecurityEvent stores a suspicious account event for later review
+func (m *Model) RecordSecurityEvent(userID uuid.UUID, eventType, details, ipAddress, userAgent string) error {
+	event := &entities.SecurityEvent{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      eventType,
+		Details:   details,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		CreatedAt: time.Now(),
+	}
+	return m.db.Create(event).Error
+}
@@ -1,10 +1,12 @@
 package models
 
 import (
+	"errors"
 	"hyperlocal/internal/entities"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateReport creates a new report for a post
@@ -18,6 +20,9 @@ func (m *Model) CreateReport(postID, userID uuid.UUID, reason string) (*entities
 	}
 
 	if err := m.db.Create(report).Error; err != nil {
+		if errors.Is(err, gorm.ErrForeignKeyViolated) {
+			return nil, entities.ErrPostNotFound
+		}
 		return nil, err
 	}
 
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"hyperlocal/internal/entities"
+	"time"
+)
+
+// PostSearchFilter describes a page of full-text search results near a location
+type PostSearchFilter struct {
+	Query string
+	// Languages are the text search configurations to search. Each post is
+	// matched with the query stemmed in its own language.
+	Languages    []string
+	Latitude     float64
+	Longitude    float64
+	RadiusMeters float64
+	// RankedAt is the time recency is measured from. It stays the same for
+	// every page of a search, so scores do not drift between pages.
+	RankedAt time.Time
+	Limit    int
+	After    *PostCursor
+}
+
+// searchScoreExpr blends text relevance with closeness and recency. Relevance
+// uses ts_rank_cd normalised into [0, 1), closeness falls linearly to 0 at the
+// edge of the radius, and recency halves roughly every five days, counted
+// from RankedAt rather than now() so keyset pagination over it stays stable.
+const searchScoreExpr = `(
+	0.6 * GREATEST(post_rank, COALESCE(comment_rank, 0))
+	+ 0.25 * (1 - distance_meters / @radius)
+	+ 0.15 * EXP(-EXTRACT(EPOCH FROM @ranked_at::timestamptz - created_at) / 604800)
+)::float8`
+
+// searchHeadlineOptions marks matches with <mark> in a snippet of about 15-35 words
+const searchHeadlineOptions = `'StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35, MaxFragments=2'`
+
+// htmlEscapeExpr escapes text for HTML before ts_headline adds its <mark> tags
+func htmlEscapeExpr(column string) string {
+	return `replace(replace(replace(` + column + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`
+}
+
+// SearchPosts runs a full-text search over posts and their comments within a
+// radius. A post matches if its content or one of its visible comments does;
+// the snippet comes from whichever ranked higher.
+func (m *Model) SearchPosts(filter PostSearchFilter) ([]entities.Post, error) {
+	var posts []entities.Post
+
+	// search stems the query once per language, and every post and comment
+	// is matched against the query in its own language. candidates uses the
+	// GIN indexes on search_vector to find matching posts and comments, then
+	// the spatial filter narrows them to the neighbourhood.
+	// websearch_to_tsquery accepts user input such as "lost dog" -cat without
+	// raising syntax errors.
+	query := `
+		WITH search AS (
+			SELECT cfg, websearch_to_tsquery(cfg, @query) AS tsq
+			FROM unnest(ARRAY[@languages]::regconfig[]) AS cfg
+		),
+		candidates AS (
+			SELECT posts.id FROM posts
+			JOIN search ON search.cfg = posts.language
+			WHERE posts.search_vector @@ search.tsq
+			UNION
+			SELECT comments.post_id FROM comments
+			JOIN search ON search.cfg = comments.language
+			WHERE comments.search_vector @@ search.tsq AND comments.deleted_at IS NULL
+		),
+		matched AS (
+			SELECT posts.*,
+				search.tsq,
+				ST_Distance(posts.location, ST_SetSRID(ST_MakePoint(@lng, @lat), 4326)::geography) AS distance_meters,
+				ts_rank_cd(posts.search_vector, search.tsq, 32) AS post_rank,
+				best_comment.id AS matched_comment_id,
+				best_comment.content AS comment_content,
+				best_comment.language AS comment_language,
+				best_comment.rank AS comment_rank
+			FROM posts
+			JOIN candidates ON candidates.id = posts.id
+			JOIN search ON search.cfg = posts.language
+			LEFT JOIN LATERAL (
+				SELECT comments.id, comments.content, comments.language,
+					ts_rank_cd(comments.search_vector, search.tsq, 32) AS rank
+				FROM comments
+				WHERE comments.post_id = posts.id
+					AND comments.deleted_at IS NULL
+					AND comments.search_vector @@ search.tsq
+				ORDER BY rank DESC
+				LIMIT 1
+			) best_comment ON true
+			WHERE ST_DWithin(posts.location, ST_SetSRID(ST_MakePoint(@lng, @lat), 4326)::geography, @radius)
+				AND posts.deleted_at IS NULL
+				AND (posts.expires_at IS NULL OR posts.expires_at > now())
+		),
+		ranked AS (
+			SELECT matched.*, ` + searchScoreExpr + ` AS score
+			FROM matched
+		)
+		SELECT ranked.*,
+			CASE WHEN matched_comment_id IS NOT NULL AND comment_rank > post_rank
+				THEN ts_headline(comment_language, ` + htmlEscapeExpr("comment_content") + `, tsq, ` + searchHeadlineOptions + `)
+				ELSE ts_headline(language, ` + htmlEscapeExpr("content") + `, tsq, ` + searchHeadlineOptions + `)
+			END AS snippet
+		FROM ranked
+	`
+	args := map[string]interface{}{
+		"query":     filter.Query,
+		"languages": filter.Languages,
+		"lng":       filter.Longitude,
+		"lat":       filter.Latitude,
+		"radius":    filter.RadiusMeters,
+		"ranked_at": filter.RankedAt,
+		"limit":     filter.Limit,
+	}
+
+	// Keyset pagination, the same way as the nearby feed
+	if filter.After != nil {
+		query += ` WHERE (score, created_at, id) < (@after_score, @after_created_at, @after_id)`
+		args["after_score"] = filter.After.Score
+		args["after_created_at"] = filter.After.CreatedAt
+		args["after_id"] = filter.After.ID
+	}
+
+	query += `
+		ORDER BY score DESC, created_at DESC, id DESC
+		LIMIT @limit
+	`
+
+	if err := m.db.Raw(query, args).Scan(&posts).Error; err != nil {
+		return nil, err
+	}
+
+	if err := m.loadPostUsers(posts); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadPostImages(posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
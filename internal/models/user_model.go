@@ -3,6 +3,7 @@ package models
 import (
 	"errors"
 	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,10 +26,14 @@ func (m *Model) CreateUser(username *string, password string) (*entities.User, e
 		ID:           uuid.New(),
 		Username:     username,
 		PasswordHash: string(hashedPassword),
+		Role:         enums.RoleUser,
 		CreatedAt:    time.Now(),
 	}
 
 	if err := m.db.Create(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, entities.ErrUsernameTaken
+		}
 		return nil, err
 	}
 
@@ -40,7 +45,7 @@ func (m *Model) GetUserByID(id uuid.UUID) (*entities.User, error) {
 	var user entities.User
 	if err := m.db.First(&user, "id = ?", id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, entities.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -52,7 +57,7 @@ func (m *Model) GetUserByUsername(username string) (*entities.User, error) {
 	var user entities.User
 	if err := m.db.First(&user, "username = ?", username).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, entities.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -66,38 +71,26 @@ func (m *Model) UpdateUser(user *entities.User) error {
 
 // BanUser sets the user's banned status
 func (m *Model) BanUser(userID uuid.UUID, banned bool) error {
-	return m.db.Model(&entities.User{}).Where("id = ?", userID).Update("is_banned", banned).Error
-}
-
-// StoreRefreshToken stores a refresh token for a user
-func (m *Model) StoreRefreshToken(userID uuid.UUID, token string, expiresAt time.Time) error {
-	refreshToken := &entities.RefreshToken{
-		ID:        uuid.New(),
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: expiresAt,
-		CreatedAt: time.Now(),
+	result := m.db.Model(&entities.User{}).Where("id = ?", userID).Update("is_banned", banned)
+	if result.Error != nil {
+		return result.Error
 	}
-	return m.db.Create(refreshToken).Error
-}
-
-// GetRefreshToken retrieves a refresh token
-func (m *Model) GetRefreshToken(token string) (*entities.RefreshToken, error) {
-	var refreshToken entities.RefreshToken
-	if err := m.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
-		return nil, err
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
 	}
-	return &refreshToken, nil
-}
-
-// DeleteRefreshToken deletes a refresh token
-func (m *Model) DeleteRefreshToken(token string) error {
-	return m.db.Where("token = ?", token).Delete(&entities.RefreshToken{}).Error
+	return nil
 }
 
-// DeleteExpiredRefreshTokens deletes all expired refresh tokens
-func (m *Model) DeleteExpiredRefreshTokens() error {
-	return m.db.Where("expires_at < ?", time.Now()).Delete(&entities.RefreshToken{}).Error
+// SetUserRole changes the role of a user
+func (m *Model) SetUserRole(userID uuid.UUID, role enums.Role) error {
+	result := m.db.Model(&entities.User{}).Where("id = ?", userID).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entities.ErrUserNotFound
+	}
+	return nil
 }
 
 // VerifyPassword checks if the provided password matches the stored hash
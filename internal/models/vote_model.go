@@ -3,109 +3,211 @@ package models
 import (
 	"errors"
 	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// VoteModel handles vote-related database operations
-type VoteModel struct{}
+// voteTarget describes something users can vote on: the table holding its
+// upvotes and downvotes counters, and the table recording who voted
+type voteTarget struct {
+	table     string
+	voteTable string
+	// column references the target from voteTable
+	column   string
+	notFound error
+}
+
+var (
+	postVotes    = voteTarget{table: "posts", voteTable: "user_post_votes", column: "post_id", notFound: entities.ErrPostNotFound}
+	commentVotes = voteTarget{table: "comments", voteTable: "user_comment_votes", column: "comment_id", notFound: entities.ErrCommentNotFound}
+)
+
+// reconcileBatchSize is how many targets ReconcileVoteCounts recounts per transaction
+const reconcileBatchSize = 500
+
+// counterColumn returns the counter a vote type is tallied in
+func counterColumn(voteType enums.VoteType) string {
+	if voteType == enums.VoteDown {
+		return "downvotes"
+	}
+	return "upvotes"
+}
 
 // VoteOnPost records a user's vote on a post
-func (m *Model) VoteOnPost(userID, postID uuid.UUID, voteType string) error {
-	// Check if user has already voted on this post
-	var existingVote entities.UserPostVote
-	err := m.db.Where("user_id = ? AND post_id = ?", userID, postID).First(&existingVote).Error
-
-	// If no vote exists, create a new one
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		vote := &entities.UserPostVote{
-			ID:        uuid.New(),
-			UserID:    userID,
-			PostID:    postID,
-			VoteType:  voteType,
-			CreatedAt: time.Now(),
-		}
+func (m *Model) VoteOnPost(userID, postID uuid.UUID, voteType enums.VoteType) error {
+	return m.vote(postVotes, userID, postID, voteType)
+}
 
-		// Begin transaction
-		tx := m.db.Begin()
+// VoteOnComment records a user's vote on a comment
+func (m *Model) VoteOnComment(userID, commentID uuid.UUID, voteType enums.VoteType) error {
+	return m.vote(commentVotes, userID, commentID, voteType)
+}
 
-		// Create the vote
-		if err := tx.Create(vote).Error; err != nil {
-			tx.Rollback()
+// vote records a user's vote on a target and keeps its counters in step.
+// A user has at most one vote per target: voting again the other way switches
+// the vote, voting the same way again returns ErrAlreadyVoted.
+//
+// The vote is written with a single upsert against the unique (user, target)
+// constraint, so concurrent requests serialise on the vote row instead of
+// racing between a read and an insert. The upsert only touches the row when
+// the vote changes, and xmax = 0 tells a fresh insert from a switched vote.
+func (m *Model) vote(target voteTarget, userID, targetID uuid.UUID, voteType enums.VoteType) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		var written []struct {
+			Inserted bool
+		}
+		err := tx.Raw(`
+			INSERT INTO `+target.voteTable+` AS v (id, user_id, `+target.column+`, vote_type, created_at)
+			VALUES (@id, @user, @target, @vote_type, @now)
+			ON CONFLICT (user_id, `+target.column+`) DO UPDATE SET vote_type = EXCLUDED.vote_type
+				WHERE v.vote_type <> EXCLUDED.vote_type
+			RETURNING (xmax = 0) AS inserted
+		`, map[string]interface{}{"id": uuid.New(), "user": userID, "target": targetID, "vote_type": voteType, "now": time.Now()}).Scan(&written).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrForeignKeyViolated) {
+				return target.notFound
+			}
 			return err
 		}
 
-		// Update the post's vote count
-		if voteType == "upvote" {
-			if err := tx.Model(&entities.Post{}).Where("id = ?", postID).Update("upvotes", gorm.Expr("upvotes + 1")).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
-		} else if voteType == "downvote" {
-			if err := tx.Model(&entities.Post{}).Where("id = ?", postID).Update("downvotes", gorm.Expr("downvotes + 1")).Error; err != nil {
-				tx.Rollback()
-				return err
-			}
+		// Nothing written means the same vote was already there
+		if len(written) == 0 {
+			return entities.ErrAlreadyVoted
 		}
 
-		// Commit transaction
-		return tx.Commit().Error
-	}
+		if written[0].Inserted {
+			counter := counterColumn(voteType)
+			return tx.Table(target.table).Where("id = ?", targetID).Update(counter, gorm.Expr(counter+" + 1")).Error
+		}
 
-	// If vote exists but is different type, update it
-	if err == nil && existingVote.VoteType != voteType {
-		// Begin transaction
-		tx := m.db.Begin()
+		// The vote switched from the other direction
+		from, to := "downvotes", "upvotes"
+		if voteType == enums.VoteDown {
+			from, to = to, from
+		}
+		return tx.Table(target.table).Where("id = ?", targetID).Updates(map[string]interface{}{
+			from: gorm.Expr(from + " - 1"),
+			to:   gorm.Expr(to + " + 1"),
+		}).Error
+	})
+}
 
-		// Update the vote type
-		if err := tx.Model(&existingVote).Update("vote_type", voteType).Error; err != nil {
-			tx.Rollback()
+// RetractPostVote removes a user's vote on a post
+func (m *Model) RetractPostVote(userID, postID uuid.UUID) error {
+	return m.retractVote(postVotes, userID, postID)
+}
+
+// retractVote removes a user's vote on a target and takes it back off the
+// target's counters. Returns ErrVoteNotFound if the user had not voted.
+func (m *Model) retractVote(target voteTarget, userID, targetID uuid.UUID) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		var removed []struct {
+			VoteType enums.VoteType
+		}
+		err := tx.Raw(`DELETE FROM `+target.voteTable+` WHERE user_id = @user AND `+target.column+` = @target RETURNING vote_type`,
+			map[string]interface{}{"user": userID, "target": targetID}).Scan(&removed).Error
+		if err != nil {
 			return err
 		}
 
-		// Update the post's vote counts
-		if existingVote.VoteType == "upvote" && voteType == "downvote" {
-			// Change from upvote to downvote
-			if err := tx.Model(&entities.Post{}).Where("id = ?", postID).Updates(map[string]interface{}{
-				"upvotes":   gorm.Expr("upvotes - 1"),
-				"downvotes": gorm.Expr("downvotes + 1"),
-			}).Error; err != nil {
-				tx.Rollback()
-				return err
+		if len(removed) == 0 {
+			return entities.ErrVoteNotFound
+		}
+
+		counter := counterColumn(removed[0].VoteType)
+		return tx.Table(target.table).Where("id = ?", targetID).Update(counter, gorm.Expr(counter+" - 1")).Error
+	})
+}
+
+// GetUserPostVotes returns the user's votes on the given posts in one query,
+// keyed by post ID. Posts the user has not voted on are absent.
+func (m *Model) GetUserPostVotes(userID uuid.UUID, postIDs []uuid.UUID) (map[uuid.UUID]enums.VoteType, error) {
+	votes := make(map[uuid.UUID]enums.VoteType, len(postIDs))
+	if len(postIDs) == 0 {
+		return votes, nil
+	}
+
+	var rows []entities.UserPostVote
+	if err := m.db.Select("post_id", "vote_type").Where("user_id = ? AND post_id IN ?", userID, postIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		votes[row.PostID] = row.VoteType
+	}
+	return votes, nil
+}
+
+// ReconcileVoteCounts recomputes the upvotes and downvotes counters of posts
+// and comments from the recorded votes and returns how many rows were off.
+// Targets are recounted in id order, a batch per transaction, so votes
+// elsewhere on the site are never held up.
+func (m *Model) ReconcileVoteCounts() (int64, error) {
+	var fixed int64
+	for _, target := range []voteTarget{postVotes, commentVotes} {
+		after := uuid.Nil
+		for {
+			ids, corrected, err := m.reconcileVoteBatch(target, after, reconcileBatchSize)
+			fixed += corrected
+			if err != nil {
+				return fixed, err
 			}
-		} else if existingVote.VoteType == "downvote" && voteType == "upvote" {
-			// Change from downvote to upvote
-			if err := tx.Model(&entities.Post{}).Where("id = ?", postID).Updates(map[string]interface{}{
-				"downvotes": gorm.Expr("downvotes - 1"),
-				"upvotes":   gorm.Expr("upvotes + 1"),
-			}).Error; err != nil {
-				tx.Rollback()
-				return err
+			if len(ids) < reconcileBatchSize {
+				break
 			}
+			after = ids[len(ids)-1]
 		}
-
-		// Commit transaction
-		return tx.Commit().Error
 	}
 
-	// If vote exists and is the same type, return error
-	return errors.New("user has already voted on this post")
+	return fixed, nil
 }
 
-// HasUserVotedOnPost checks if a user has already voted on a post
-func (m *Model) HasUserVotedOnPost(userID, postID uuid.UUID) (bool, string, error) {
-	var vote entities.UserPostVote
-	err := m.db.Where("user_id = ? AND post_id = ?", userID, postID).First(&vote).Error
+// reconcileVoteBatch recounts the next batch of targets after the given id,
+// returning the ids it covered and how many counters it corrected.
+//
+// The batch's rows are locked before counting, in a statement of their own.
+// A vote that already moved a counter has committed by the time the lock is
+// granted, so the count sees it; one that has not yet moved its counter waits
+// for this transaction and then applies its change on top of the new total.
+func (m *Model) reconcileVoteBatch(target voteTarget, after uuid.UUID, limit int) ([]uuid.UUID, int64, error) {
+	var ids []uuid.UUID
+	var corrected int64
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`SELECT id FROM `+target.table+` WHERE id > ? ORDER BY id LIMIT ? FOR UPDATE`, after, limit).
+			Scan(&ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
 
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return false, "", nil
-	}
+		result := tx.Exec(`
+			UPDATE `+target.table+` AS t
+			SET upvotes = counts.upvotes, downvotes = counts.downvotes
+			FROM (
+				SELECT t.id,
+					COUNT(v.id) FILTER (WHERE v.vote_type = 'upvote') AS upvotes,
+					COUNT(v.id) FILTER (WHERE v.vote_type = 'downvote') AS downvotes
+				FROM `+target.table+` t
+				LEFT JOIN `+target.voteTable+` v ON v.`+target.column+` = t.id
+				WHERE t.id IN ?
+				GROUP BY t.id
+			) counts
+			WHERE t.id = counts.id
+				AND (t.upvotes IS DISTINCT FROM counts.upvotes OR t.downvotes IS DISTINCT FROM counts.downvotes)
+		`, ids)
+		if result.Error != nil {
+			return result.Error
+		}
 
+		corrected = result.RowsAffected
+		return nil
+	})
 	if err != nil {
-		return false, "", err
+		return nil, 0, err
 	}
 
-	return true, vote.VoteType, nil
+	return ids, corrected, nil
 }
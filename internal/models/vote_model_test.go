@@ -0,0 +1,91 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+)
+
+func TestConcurrentPostVotesKeepCountersInStep(t *testing.T) {
+	m, db := testModel(t)
+
+	author := createTestUser(t, m)
+	post := &entities.Post{
+		UserID:    author.ID,
+		Content:   "concurrent votes",
+		Category:  enums.CategoryGeneral,
+		Latitude:  51.5072,
+		Longitude: -0.1276,
+	}
+	if err := m.CreatePost(post); err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+
+	const voters = 8
+	const rounds = 20
+	users := make([]*entities.User, voters)
+	for i := range users {
+		users[i] = createTestUser(t, m)
+	}
+
+	// Every voter hammers the post from several goroutines at once, cycling
+	// through upvotes, downvotes and retractions so that switches, repeats
+	// and retractions of the same vote row race with each other
+	var wg sync.WaitGroup
+	errs := make(chan error, voters*3*rounds)
+	for _, user := range users {
+		for worker := 0; worker < 3; worker++ {
+			wg.Add(1)
+			go func(worker int) {
+				defer wg.Done()
+				for i := 0; i < rounds; i++ {
+					var err error
+					switch (worker + i) % 3 {
+					case 0:
+						err = m.VoteOnPost(user.ID, post.ID, enums.VoteUp)
+					case 1:
+						err = m.VoteOnPost(user.ID, post.ID, enums.VoteDown)
+					default:
+						err = m.RetractPostVote(user.ID, post.ID)
+					}
+					if err != nil && !errors.Is(err, entities.ErrAlreadyVoted) && !errors.Is(err, entities.ErrVoteNotFound) {
+						errs <- err
+					}
+				}
+			}(worker)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("vote: %v", err)
+	}
+
+	var counters struct {
+		Upvotes   int
+		Downvotes int
+	}
+	if err := db.Table("posts").Select("upvotes", "downvotes").Where("id = ?", post.ID).Scan(&counters).Error; err != nil {
+		t.Fatalf("read counters: %v", err)
+	}
+
+	var recorded struct {
+		Upvotes   int
+		Downvotes int
+	}
+	err := db.Raw(`
+		SELECT COUNT(*) FILTER (WHERE vote_type = ?) AS upvotes,
+		       COUNT(*) FILTER (WHERE vote_type = ?) AS downvotes
+		FROM user_post_votes WHERE post_id = ?
+	`, enums.VoteUp, enums.VoteDown, post.ID).Scan(&recorded).Error
+	if err != nil {
+		t.Fatalf("count votes: %v", err)
+	}
+
+	if counters != recorded {
+		t.Fatalf("counters %+v do not match recorded votes %+v", counters, recorded)
+	}
+}
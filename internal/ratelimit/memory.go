@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process memory. Buckets that have refilled
+// completely carry no information and are evicted periodically.
+type MemoryStore struct {
+	mu            sync.Mutex
+	buckets       map[string]*memoryBucket
+	sweepInterval time.Duration
+	lastSweep     time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	fullAt time.Time
+}
+
+// NewMemoryStore creates an in-memory Store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:       make(map[string]*memoryBucket),
+		sweepInterval: time.Minute,
+	}
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= s.sweepInterval {
+		s.sweep(now)
+	}
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &memoryBucket{bucket: bucket{tokens: float64(policy.Limit), updated: now}}
+		s.buckets[key] = b
+	}
+
+	result := b.take(policy, now)
+	b.fullAt = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+// sweep removes buckets that are full again
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is a token bucket holding up to Limit tokens that refills completely
+// over Period, e.g. 3 per hour allows a burst of 3 and then one every 20 minutes
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// rate returns the refill rate in tokens per second
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// String formats the policy for the RateLimit-Policy header
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int(p.Period.Seconds()))
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+	// RetryAfter is how long until the next token is available when not allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps bucket state. Take must check and consume a token atomically
+// so that a shared backend can serve several instances.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// Route groups with their own policy
+const (
+	GroupPosts    = "posts"
+	GroupVotes    = "votes"
+	GroupComments = "comments"
+	GroupReports  = "reports"
+	GroupAuth     = "auth"
+)
+
+// DefaultPolicies returns the built-in policy for each route group
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		GroupPosts:    {Name: GroupPosts, Limit: 3, Period: time.Hour},
+		GroupVotes:    {Name: GroupVotes, Limit: 60, Period: time.Minute},
+		GroupComments: {Name: GroupComments, Limit: 20, Period: 10 * time.Minute},
+		GroupReports:  {Name: GroupReports, Limit: 10, Period: time.Hour},
+		GroupAuth:     {Name: GroupAuth, Limit: 10, Period: time.Minute},
+	}
+}
+
+// PoliciesFromEnv returns the default policies overridden by RATE_LIMIT_<GROUP>
+// variables of the form "<limit>/<period>", e.g. RATE_LIMIT_POSTS=5/1h
+func PoliciesFromEnv() (map[string]Policy, error) {
+	policies := DefaultPolicies()
+
+	for group, policy := range policies {
+		envKey := "RATE_LIMIT_" + strings.ToUpper(group)
+		value := os.Getenv(envKey)
+		if value == "" {
+			continue
+		}
+
+		limitStr, periodStr, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("%s must look like <limit>/<period>", envKey)
+		}
+
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("%s has an invalid limit", envKey)
+		}
+
+		period, err := time.ParseDuration(periodStr)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("%s has an invalid period", envKey)
+		}
+
+		policy.Limit = limit
+		policy.Period = period
+		policies[group] = policy
+	}
+
+	return policies, nil
+}
+
+// bucket is the state of one token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take refills the bucket for the time elapsed and tries to consume one token
+func (b *bucket) take(policy Policy, now time.Time) Result {
+	rate := policy.rate()
+	capacity := float64(policy.Limit)
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+		b.updated = now
+	}
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = secondsToDuration((capacity - b.tokens) / rate)
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
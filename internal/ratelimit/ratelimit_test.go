@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Name: "posts", Limit: 3, Period: time.Hour}
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	take := func() Result {
+		t.Helper()
+		result, err := store.Take(context.Background(), "posts:user:alice", policy, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// The full burst is allowed at once
+	for want := 2; want >= 0; want-- {
+		result := take()
+		if !result.Allowed || result.Remaining != want {
+			t.Fatalf("burst: got %+v, want allowed with %d remaining", result, want)
+		}
+	}
+
+	// Then one token refills every Period/Limit
+	result := take()
+	if result.Allowed {
+		t.Fatalf("over the limit: got %+v, want denied", result)
+	}
+	if !roughly(result.RetryAfter, 20*time.Minute) {
+		t.Errorf("retry after %v, want 20m", result.RetryAfter)
+	}
+	if !roughly(result.ResetAfter, time.Hour) {
+		t.Errorf("reset after %v, want 1h", result.ResetAfter)
+	}
+
+	now = now.Add(20 * time.Minute)
+	if result := take(); !result.Allowed {
+		t.Errorf("after one refill: got %+v, want allowed", result)
+	}
+	if result := take(); result.Allowed {
+		t.Errorf("refill used up: got %+v, want denied", result)
+	}
+
+	// Other keys have their own bucket
+	other, err := store.Take(context.Background(), "posts:user:bob", policy, now)
+	if err != nil || !other.Allowed {
+		t.Errorf("other key: got %+v, %v, want allowed", other, err)
+	}
+}
+
+// roughly compares durations computed from floating point token counts
+func roughly(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Millisecond && diff < time.Millisecond
+}
+
+func TestPoliciesFromEnv(t *testing.T) {
+	t.Setenv("RATE_LIMIT_POSTS", "5/30m")
+
+	policies, err := PoliciesFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := policies[GroupPosts]; got.Limit != 5 || got.Period != 30*time.Minute || got.Name != GroupPosts {
+		t.Errorf("posts policy = %+v, want 5 per 30m", got)
+	}
+	if got, want := policies[GroupVotes], DefaultPolicies()[GroupVotes]; got != want {
+		t.Errorf("votes policy = %+v, want the default %+v", got, want)
+	}
+
+	for _, value := range []string{"5", "0/1h", "x/1h", "5/soon", "5/-1h"} {
+		t.Setenv("RATE_LIMIT_POSTS", value)
+		if _, err := PoliciesFromEnv(); err == nil {
+			t.Errorf("RATE_LIMIT_POSTS=%q accepted", value)
+		}
+	}
+}
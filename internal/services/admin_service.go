@@ -1,10 +1,54 @@
 package services
 
 import (
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+
 	"github.com/google/uuid"
 )
 
+// SetUserRoleRequest represents the request body for changing a user's role
+type SetUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
 // BanUser bans a user
 func (s *service) BanUser(userID uuid.UUID) error {
 	return s.model.BanUser(userID, true)
 }
+
+// SetUserRole grants a role to a user. Setting the role back to "user" revokes
+// any elevated permissions. Admins cannot change their own role so the last
+// admin cannot lock everyone out.
+func (s *service) SetUserRole(actorID, userID uuid.UUID, role string) error {
+	r := enums.Role(role)
+	if !r.IsValid() {
+		return entities.ErrInvalidRole
+	}
+
+	if actorID == userID {
+		return entities.ErrCannotChangeOwnRole
+	}
+
+	return s.model.SetUserRole(userID, r)
+}
+
+// ClearLoginLockout lifts a lockout and forgets the failed logins of a user
+func (s *service) ClearLoginLockout(userID uuid.UUID) error {
+	user, err := s.model.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Username == nil {
+		return nil
+	}
+
+	return s.throttle.clear(*user.Username)
+}
+
+// ReconcileVoteCounts recomputes post and comment vote counters from the
+// recorded votes and returns how many were corrected
+func (s *service) ReconcileVoteCounts() (int64, error) {
+	return s.model.ReconcileVoteCounts()
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
-	"errors"
-	"os"
+	"fmt"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/keys"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -26,6 +28,27 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// LogoutRequest represents the request body for logging out
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// ClientInfo identifies the client a session is created from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// SessionResponse represents an active login session
+type SessionResponse struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"user_agent"`
+	IPAddress string    `json:"ip_address"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
 // TokenResponse represents the response for successful authentication
 type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
@@ -37,15 +60,23 @@ type TokenResponse struct {
 type JWTClaims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	// SessionID is the refresh token family the access token was issued with
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 // Register creates a new user account
-func (s *service) Register(req RegisterRequest) (*TokenResponse, error) {
+func (s *service) Register(req RegisterRequest, client ClientInfo) (*TokenResponse, error) {
+	// Slow down clients probing for existing usernames
+	if err := s.throttle.check(throttleKeys("", client.IPAddress)...); err != nil {
+		return nil, err
+	}
+
 	// Check if username is already taken
 	_, err := s.model.GetUserByUsername(req.Username)
 	if err == nil {
-		return nil, errors.New("username already taken")
+		s.throttle.recordFailure("", client.IPAddress)
+		return nil, entities.ErrUsernameTaken
 	}
 
 	// Create the user
@@ -56,44 +87,63 @@ func (s *service) Register(req RegisterRequest) (*TokenResponse, error) {
 	}
 
 	// Generate tokens
-	return s.generateTokens(user.ID)
+	return s.generateTokens(user, client, uuid.Nil)
 }
 
 // Login authenticates a user and returns tokens
-func (s *service) Login(req LoginRequest) (*TokenResponse, error) {
-	// Find the user
+func (s *service) Login(req LoginRequest, client ClientInfo) (*TokenResponse, error) {
+	// Refuse the attempt while the username or client IP is locked or backing off
+	if err := s.throttle.check(throttleKeys(req.Username, client.IPAddress)...); err != nil {
+		return nil, err
+	}
+
+	// Find the user. Unknown usernames count as failures too so that
+	// lockouts do not reveal which accounts exist.
 	user, err := s.model.GetUserByUsername(req.Username)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		s.throttle.recordFailure(req.Username, client.IPAddress)
+		return nil, entities.ErrInvalidCredentials
 	}
 
-	// Check if user is banned
+	// Verify password
+	if !s.model.VerifyPassword(user, req.Password) {
+		s.throttle.recordFailure(req.Username, client.IPAddress)
+		return nil, entities.ErrInvalidCredentials
+	}
+
+	// Only reveal the ban to someone who knows the password
 	if user.IsBanned {
-		return nil, errors.New("account is banned")
+		return nil, entities.ErrAccountBanned
 	}
 
-	// Verify password
-	if !s.model.VerifyPassword(user, req.Password) {
-		return nil, errors.New("invalid credentials")
+	// A successful login clears the username's failures, but not the IP's
+	if err := s.throttle.clear(req.Username); err != nil {
+		log.Println("Failed to clear login throttle", err)
 	}
 
 	// Generate tokens
-	return s.generateTokens(user.ID)
+	return s.generateTokens(user, client, uuid.Nil)
 }
 
 // RefreshToken refreshes the access token using a refresh token
-func (s *service) RefreshToken(req RefreshTokenRequest) (*TokenResponse, error) {
+func (s *service) RefreshToken(req RefreshTokenRequest, client ClientInfo) (*TokenResponse, error) {
 	// Get the refresh token from the database
 	refreshToken, err := s.model.GetRefreshToken(req.RefreshToken)
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		return nil, entities.ErrInvalidRefreshToken
+	}
+
+	// A token that was already rotated is being replayed: either the client
+	// or an attacker holds a stolen copy, so the whole session is revoked
+	if refreshToken.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(refreshToken, client)
 	}
 
 	// Check if the token is expired
 	if refreshToken.ExpiresAt.Before(time.Now()) {
 		// Delete the expired token
 		s.model.DeleteRefreshToken(req.RefreshToken)
-		return nil, errors.New("refresh token expired")
+		return nil, entities.ErrRefreshTokenExpired
 	}
 
 	// Get the user
@@ -104,34 +154,107 @@ func (s *service) RefreshToken(req RefreshTokenRequest) (*TokenResponse, error)
 
 	// Check if user is banned
 	if user.IsBanned {
-		return nil, errors.New("account is banned")
+		return nil, entities.ErrAccountBanned
 	}
 
-	// Delete the old refresh token
-	if err := s.model.DeleteRefreshToken(req.RefreshToken); err != nil {
+	// Rotate the old refresh token. Losing this race to a concurrent
+	// request with the same token is treated as reuse as well.
+	rotated, err := s.model.MarkRefreshTokenRotated(refreshToken.ID)
+	if err != nil {
 		return nil, err
 	}
+	if !rotated {
+		return nil, s.revokeReusedFamily(refreshToken, client)
+	}
+
+	// Generate new tokens in the same family
+	return s.generateTokens(user, client, refreshToken.FamilyID)
+}
 
-	// Generate new tokens
-	return s.generateTokens(user.ID)
+// revokeReusedFamily revokes every token of a replayed refresh token's family
+// and records the event as a suspected theft
+func (s *service) revokeReusedFamily(refreshToken *entities.RefreshToken, client ClientInfo) error {
+	if err := s.model.DeleteRefreshTokenFamily(refreshToken.FamilyID); err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("rotated refresh token %s of session %s presented again", refreshToken.ID, refreshToken.FamilyID)
+	if err := s.model.RecordSecurityEvent(refreshToken.UserID, "refresh_token_reuse", details, client.IPAddress, client.UserAgent); err != nil {
+		log.Println("Failed to record security event", err)
+	}
+
+	return entities.ErrRefreshTokenReused
+}
+
+// Logout revokes the session of the given refresh token. Unknown tokens are
+// ignored so logging out twice is not an error.
+func (s *service) Logout(req LogoutRequest) error {
+	refreshToken, err := s.model.GetRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil
+	}
+	return s.model.DeleteRefreshTokenFamily(refreshToken.FamilyID)
 }
 
-// generateTokens generates access and refresh tokens for a user
-func (s *service) generateTokens(userID uuid.UUID) (*TokenResponse, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET not set")
+// GetSessions lists the active sessions of a user, flagging the one the caller is using
+func (s *service) GetSessions(userID uuid.UUID, currentSessionID string) ([]SessionResponse, error) {
+	tokens, err := s.model.GetActiveRefreshTokens(userID)
+	if err != nil {
+		return nil, err
 	}
 
+	response := make([]SessionResponse, len(tokens))
+	for i, token := range tokens {
+		response[i] = SessionResponse{
+			ID:        token.FamilyID.String(),
+			UserAgent: token.UserAgent,
+			IPAddress: token.IPAddress,
+			CreatedAt: token.SessionStartedAt,
+			ExpiresAt: token.ExpiresAt,
+			Current:   token.FamilyID.String() == currentSessionID,
+		}
+	}
+
+	return response, nil
+}
+
+// RevokeSession revokes one session of a user
+func (s *service) RevokeSession(userID, sessionID uuid.UUID) error {
+	return s.model.DeleteUserRefreshTokenFamily(userID, sessionID)
+}
+
+// RevokeAllSessions logs a user out everywhere
+func (s *service) RevokeAllSessions(userID uuid.UUID) error {
+	return s.model.DeleteUserRefreshTokens(userID)
+}
+
+// generateTokens generates access and refresh tokens for a user. The refresh
+// token joins the given rotation family, or starts a new one if it is uuid.Nil.
+func (s *service) generateTokens(user *entities.User, client ClientInfo, familyID uuid.UUID) (*TokenResponse, error) {
+	userID := user.ID
+
 	// Set token expiration times
 	accessTokenExpiry := time.Now().Add(15 * time.Minute)
 	refreshTokenExpiry := time.Now().Add(30 * 24 * time.Hour) // 30 days
 
+	// Generate a refresh token (a random UUID)
+	refreshToken := uuid.New().String()
+
+	if familyID == uuid.Nil {
+		familyID = uuid.New()
+	}
+
+	// Store the refresh token in the database, its family doubles as the session record
+	session, err := s.model.StoreRefreshToken(userID, familyID, refreshToken, refreshTokenExpiry, client.UserAgent, client.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the claims for the access token
 	claims := JWTClaims{
-		UserID: userID.String(),
-		Role:   "user", // Default role
+		UserID:    userID.String(),
+		Role:      string(user.Role),
+		SessionID: session.FamilyID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(accessTokenExpiry),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -141,21 +264,12 @@ func (s *service) generateTokens(userID uuid.UUID) (*TokenResponse, error) {
 		},
 	}
 
-	// Create the access token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(jwtSecret))
+	// Sign the access token with the current key
+	accessToken, err := s.keys.Sign(claims)
 	if err != nil {
 		return nil, err
 	}
 
-	// Generate a refresh token (a random UUID)
-	refreshToken := uuid.New().String()
-
-	// Store the refresh token in the database
-	if err := s.model.StoreRefreshToken(userID, refreshToken, refreshTokenExpiry); err != nil {
-		return nil, err
-	}
-
 	// Return the tokens
 	return &TokenResponse{
 		AccessToken:  accessToken,
@@ -166,35 +280,28 @@ func (s *service) generateTokens(userID uuid.UUID) (*TokenResponse, error) {
 
 // ValidateToken validates a JWT token and returns the claims
 func (s *service) ValidateToken(tokenString string) (*JWTClaims, error) {
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET not set")
-	}
-
-	// Parse the token
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(jwtSecret), nil
-	})
+	// Parse the token, resolving the verification key from its kid header
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, s.keys.Keyfunc, jwt.WithValidMethods(s.keys.Methods()))
 
 	if err != nil {
-		return nil, err
+		return nil, entities.ErrInvalidToken
 	}
 
 	// Validate the token
 	if !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, entities.ErrInvalidToken
 	}
 
 	// Get the claims
 	claims, ok := token.Claims.(*JWTClaims)
 	if !ok {
-		return nil, errors.New("invalid token claims")
+		return nil, entities.ErrInvalidToken
 	}
 
 	return claims, nil
 }
+
+// JWKS returns the public keys other services use to verify access tokens
+func (s *service) JWKS() keys.JWKSet {
+	return s.keys.JWKS()
+}
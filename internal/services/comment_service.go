@@ -1,32 +1,210 @@
 package services
 
 import (
+	"errors"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// MaxCommentDepth is the deepest a reply can be nested. Top-level comments
+// have depth 0.
+const MaxCommentDepth = 5
+
+// CommentSort selects how comment threads are ordered
+type CommentSort string
+
+const (
+	// CommentSortNew puts the newest top-level comments first and replies in conversation order
+	CommentSortNew CommentSort = "new"
+	// CommentSortTop orders comments and replies by net votes
+	CommentSortTop CommentSort = "top"
+)
+
 // CreateCommentRequest represents the request body for creating a comment
 type CreateCommentRequest struct {
 	Content string `json:"content" validate:"required,min=1,max=500"`
+	// ParentID makes the comment a reply to another comment on the same post
+	ParentID *string `json:"parent_id,omitempty" validate:"omitempty,uuid"`
+	// Anonymous hides the author behind their pseudonym on the post
+	Anonymous bool `json:"anonymous,omitempty"`
 }
 
-// CommentResponse represents the response for a comment
+// CommentResponse represents the response for a comment. Comment lists are
+// flattened threads: each reply directly follows its parent and carries its depth.
 type CommentResponse struct {
-	ID        string    `json:"id"`
-	Content   string    `json:"content"`
-	Username  *string   `json:"username"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string    `json:"id"`
+	ParentID   *string   `json:"parent_id,omitempty"`
+	Depth      int       `json:"depth"`
+	Content    string    `json:"content"`
+	Username   *string   `json:"username"`
+	ReplyCount int       `json:"reply_count"`
+	Upvotes    int       `json:"upvotes"`
+	Downvotes  int       `json:"downvotes"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Pseudonym replaces Username on anonymous comments, such as "Neighbour #3"
+	Pseudonym string `json:"pseudonym,omitempty"`
+	// AuthorID is only set in moderator views, which also show the username
+	// of anonymous authors
+	AuthorID string `json:"author_id,omitempty"`
+	// Deleted marks a removed comment kept as a placeholder because it has replies
+	Deleted bool `json:"deleted,omitempty"`
+	// Deletion is only set in moderator views of removed comments
+	Deletion *DeletionResponse `json:"deletion,omitempty"`
+}
+
+// newCommentResponse converts a comment with its user loaded into the
+// response format, hiding the author of an anonymous comment
+func newCommentResponse(comment *entities.Comment) CommentResponse {
+	response := CommentResponse{
+		ID:         comment.ID.String(),
+		Depth:      comment.Depth,
+		Content:    comment.Content,
+		Username:   comment.User.Username,
+		ReplyCount: comment.ReplyCount,
+		Upvotes:    comment.Upvotes,
+		Downvotes:  comment.Downvotes,
+		CreatedAt:  comment.CreatedAt,
+	}
+	if comment.ParentID != nil {
+		parentID := comment.ParentID.String()
+		response.ParentID = &parentID
+	}
+	if comment.IsAnonymous {
+		response.Username = nil
+		response.Pseudonym = pseudonymName(comment.Pseudonym)
+	}
+	return response
+}
+
+// threadComments orders comments depth first so every reply follows its
+// parent. Given comments ordered oldest first, CommentSortNew puts top-level
+// comments newest first and replies oldest first, and CommentSortTop orders
+// siblings by net votes, breaking ties the same way. With pruneDeleted,
+// removed comments are dropped unless they still have visible replies.
+func threadComments(comments []entities.Comment, order CommentSort, pruneDeleted bool) []*entities.Comment {
+	var roots []*entities.Comment
+	replies := make(map[uuid.UUID][]*entities.Comment)
+	for i := range comments {
+		comment := &comments[i]
+		if comment.ParentID == nil {
+			roots = append(roots, comment)
+		} else {
+			replies[*comment.ParentID] = append(replies[*comment.ParentID], comment)
+		}
+	}
+
+	for i, j := 0, len(roots)-1; i < j; i, j = i+1, j-1 {
+		roots[i], roots[j] = roots[j], roots[i]
+	}
+
+	if order == CommentSortTop {
+		byScore := func(siblings []*entities.Comment) {
+			sort.SliceStable(siblings, func(i, j int) bool {
+				return siblings[i].Upvotes-siblings[i].Downvotes > siblings[j].Upvotes-siblings[j].Downvotes
+			})
+		}
+		byScore(roots)
+		for _, siblings := range replies {
+			byScore(siblings)
+		}
+	}
+
+	ordered := make([]*entities.Comment, 0, len(comments))
+
+	// walk appends a comment and its replies, and reports whether any of them is visible
+	var walk func(comment *entities.Comment) bool
+	walk = func(comment *entities.Comment) bool {
+		start := len(ordered)
+		ordered = append(ordered, comment)
+
+		visible := comment.DeletedAt == nil
+		for _, reply := range replies[comment.ID] {
+			if walk(reply) {
+				visible = true
+			}
+		}
+
+		if pruneDeleted && !visible {
+			ordered = ordered[:start]
+		}
+		return visible
+	}
+
+	for _, root := range roots {
+		walk(root)
+	}
+
+	return ordered
+}
+
+// livePost returns ErrPostNotFound if the post does not exist, has been
+// deleted or has expired
+func (s *service) livePost(postID uuid.UUID) (*entities.Post, error) {
+	post, err := s.model.GetPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	if post.DeletedAt != nil || isExpired(post) {
+		return nil, entities.ErrPostNotFound
+	}
+	return post, nil
 }
 
 // CreateComment creates a new comment on a post
 func (s *service) CreateComment(req CreateCommentRequest, postID, userID uuid.UUID) (*CommentResponse, error) {
-	// Create the comment
-	comment, err := s.model.CreateComment(postID, userID, req.Content)
+	post, err := s.livePost(postID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Replies must target a visible comment on the same post
+	var parentID *uuid.UUID
+	depth := 0
+	if req.ParentID != nil {
+		id, err := uuid.Parse(*req.ParentID)
+		if err != nil {
+			return nil, entities.ErrInvalidParentComment
+		}
+
+		parent, err := s.model.GetCommentByID(id)
+		if errors.Is(err, entities.ErrCommentNotFound) {
+			return nil, entities.ErrInvalidParentComment
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if parent.PostID != postID || parent.DeletedAt != nil {
+			return nil, entities.ErrInvalidParentComment
+		}
+
+		if parent.Depth >= MaxCommentDepth {
+			return nil, entities.ErrCommentTooDeep
+		}
+
+		parentID = &parent.ID
+		depth = parent.Depth + 1
+	}
+
+	// Create the comment, stemmed for search like its post
+	comment := &entities.Comment{
+		PostID:   postID,
+		UserID:   userID,
+		Content:  req.Content,
+		Language: post.Language,
+		ParentID: parentID,
+		Depth:    depth,
+
+		IsAnonymous: req.Anonymous,
+	}
+	if err := s.model.CreateComment(comment); err != nil {
+		return nil, err
+	}
+
 	// Get the user
 	user, err := s.model.GetUserByID(userID)
 	if err != nil {
@@ -34,16 +212,28 @@ func (s *service) CreateComment(req CreateCommentRequest, postID, userID uuid.UU
 	}
 
 	// Return the response
-	return &CommentResponse{
-		ID:        comment.ID.String(),
-		Content:   comment.Content,
-		Username:  user.Username,
-		CreatedAt: comment.CreatedAt,
-	}, nil
+	comment.User = *user
+	response := newCommentResponse(comment)
+	return &response, nil
 }
 
-// GetCommentsByPostID retrieves all comments for a post
-func (s *service) GetCommentsByPostID(postID uuid.UUID) ([]CommentResponse, error) {
+// GetCommentsByPostID retrieves the visible comments for a post as a
+// flattened thread. Removed comments with visible replies are kept as
+// placeholders without their content or author.
+func (s *service) GetCommentsByPostID(postID uuid.UUID, order string) ([]CommentResponse, error) {
+	sortOrder := CommentSort(order)
+	switch sortOrder {
+	case "":
+		sortOrder = CommentSortNew
+	case CommentSortNew, CommentSortTop:
+	default:
+		return nil, entities.ErrInvalidCommentSort
+	}
+
+	if _, err := s.livePost(postID); err != nil {
+		return nil, err
+	}
+
 	// Get comments
 	comments, err := s.model.GetCommentsByPostID(postID)
 	if err != nil {
@@ -51,15 +241,92 @@ func (s *service) GetCommentsByPostID(postID uuid.UUID) ([]CommentResponse, erro
 	}
 
 	// Convert to response format
-	response := make([]CommentResponse, len(comments))
-	for i, comment := range comments {
-		response[i] = CommentResponse{
-			ID:        comment.ID.String(),
-			Content:   comment.Content,
-			Username:  comment.User.Username,
-			CreatedAt: comment.CreatedAt,
+	thread := threadComments(comments, sortOrder, true)
+	response := make([]CommentResponse, len(thread))
+	for i, comment := range thread {
+		response[i] = newCommentResponse(comment)
+		if comment.DeletedAt != nil {
+			response[i].Content = ""
+			response[i].Username = nil
+			response[i].Pseudonym = ""
+			response[i].Deleted = true
 		}
 	}
 
 	return response, nil
 }
+
+// GetCommentsForModeration retrieves every comment for a post, including
+// removed ones along with who removed them
+func (s *service) GetCommentsForModeration(postID uuid.UUID) ([]CommentResponse, error) {
+	if _, err := s.model.GetPostByID(postID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.model.GetCommentsByPostID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := threadComments(comments, CommentSortNew, false)
+	response := make([]CommentResponse, len(thread))
+	for i, comment := range thread {
+		response[i] = newCommentResponse(comment)
+		response[i].Username = comment.User.Username
+		response[i].AuthorID = comment.UserID.String()
+		response[i].Deleted = comment.DeletedAt != nil
+		response[i].Deletion = newDeletionResponse(comment.DeletedAt, comment.DeletedBy, comment.DeletionReason)
+	}
+
+	return response, nil
+}
+
+// DeleteOwnComment removes a comment on behalf of its author
+func (s *service) DeleteOwnComment(req DeleteRequest, postID, commentID, userID uuid.UUID) error {
+	if _, err := s.livePost(postID); err != nil {
+		return err
+	}
+
+	comment, err := s.model.GetCommentByID(commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.PostID != postID || comment.DeletedAt != nil {
+		return entities.ErrCommentNotFound
+	}
+
+	if comment.UserID != userID {
+		return entities.ErrNotAuthor
+	}
+
+	return s.model.DeleteComment(commentID, userID, req.Reason)
+}
+
+// UpvoteComment upvotes a comment
+func (s *service) UpvoteComment(postID, commentID, userID uuid.UUID) error {
+	return s.voteOnComment(postID, commentID, userID, enums.VoteUp)
+}
+
+// DownvoteComment downvotes a comment
+func (s *service) DownvoteComment(postID, commentID, userID uuid.UUID) error {
+	return s.voteOnComment(postID, commentID, userID, enums.VoteDown)
+}
+
+// voteOnComment records a vote on a visible comment of the given post
+func (s *service) voteOnComment(postID, commentID, userID uuid.UUID, voteType enums.VoteType) error {
+	if _, err := s.livePost(postID); err != nil {
+		return err
+	}
+
+	comment, err := s.model.GetCommentByID(commentID)
+	if err != nil {
+		return err
+	}
+
+	if comment.PostID != postID || comment.DeletedAt != nil {
+		return entities.ErrCommentNotFound
+	}
+
+	return s.model.VoteOnComment(userID, commentID, voteType)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"hyperlocal/internal/entities/enums"
+	"hyperlocal/internal/env"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultPostEditWindow is how long after creation the author may edit a post
+	DefaultPostEditWindow = 15 * time.Minute
+	// DefaultVoteReconcileInterval is how often vote counters are recounted
+	DefaultVoteReconcileInterval = time.Hour
+	// DefaultSearchLanguage stems posts and queries that do not name a language
+	DefaultSearchLanguage = "english"
+)
+
+// Config holds tunable service settings
+type Config struct {
+	// PostEditWindow is how long after creation the author may edit a post
+	PostEditWindow time.Duration
+	// VoteReconcileInterval is how often vote counters are recounted from the
+	// recorded votes; zero disables the job
+	VoteReconcileInterval time.Duration
+	// SearchLanguage is the default text search configuration, one of SearchLanguages
+	SearchLanguage string
+	// MaxPostImages is how many images a post may carry
+	MaxPostImages int
+	// MaxImageBytes is the largest accepted upload per image
+	MaxImageBytes int64
+	// MinLocationPrecision is the finest location precision posts may use;
+	// finer requests are coarsened to it, as are stored posts at startup
+	MinLocationPrecision enums.LocationPrecision
+	// ExpirySweepInterval is how often expired posts are purged; zero
+	// disables the job
+	ExpirySweepInterval time.Duration
+	// ExpiredPostRetention is how long expired posts stay available to
+	// moderators before they are purged
+	ExpiredPostRetention time.Duration
+}
+
+// DefaultConfig returns the settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		PostEditWindow:        DefaultPostEditWindow,
+		VoteReconcileInterval: DefaultVoteReconcileInterval,
+		SearchLanguage:        DefaultSearchLanguage,
+		MaxPostImages:         DefaultMaxPostImages,
+		MaxImageBytes:         DefaultMaxImageBytes,
+		MinLocationPrecision:  enums.PrecisionExact,
+		ExpirySweepInterval:   DefaultExpirySweepInterval,
+		ExpiredPostRetention:  DefaultExpiredPostRetention,
+	}
+}
+
+// ConfigFromEnv builds the service configuration from the environment.
+// POST_EDIT_WINDOW, VOTE_RECONCILE_INTERVAL, EXPIRY_SWEEP_INTERVAL and
+// EXPIRED_POST_RETENTION take durations such as "30m",
+// SEARCH_LANGUAGE one of SearchLanguages, MAX_POST_IMAGES and MAX_IMAGE_BYTES
+// non-negative integers and MIN_LOCATION_PRECISION one of exact, street or
+// neighbourhood.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+
+	if value := os.Getenv("SEARCH_LANGUAGE"); value != "" {
+		if !isSearchLanguage(value) {
+			return Config{}, fmt.Errorf("invalid SEARCH_LANGUAGE %q", value)
+		}
+		cfg.SearchLanguage = value
+	}
+
+	if value := os.Getenv("MIN_LOCATION_PRECISION"); value != "" {
+		precision := enums.LocationPrecision(value)
+		if !precision.IsValid() {
+			return Config{}, fmt.Errorf("invalid MIN_LOCATION_PRECISION %q", value)
+		}
+		cfg.MinLocationPrecision = precision
+	}
+
+	if err := env.Duration("POST_EDIT_WINDOW", &cfg.PostEditWindow); err != nil {
+		return Config{}, err
+	}
+	if err := env.Duration("VOTE_RECONCILE_INTERVAL", &cfg.VoteReconcileInterval); err != nil {
+		return Config{}, err
+	}
+	if err := env.Duration("EXPIRY_SWEEP_INTERVAL", &cfg.ExpirySweepInterval); err != nil {
+		return Config{}, err
+	}
+	if err := env.Duration("EXPIRED_POST_RETENTION", &cfg.ExpiredPostRetention); err != nil {
+		return Config{}, err
+	}
+
+	maxImages := int64(cfg.MaxPostImages)
+	if err := env.Int("MAX_POST_IMAGES", &maxImages); err != nil {
+		return Config{}, err
+	}
+	cfg.MaxPostImages = int(maxImages)
+	if err := env.Int("MAX_IMAGE_BYTES", &cfg.MaxImageBytes); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
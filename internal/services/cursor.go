@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/base64"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// searchCursorScope tags search cursors so they cannot be replayed against the feed
+const searchCursorScope = "search"
+
+// encodePostCursor turns a feed position into an opaque string for clients.
+// The sort mode is included so a cursor cannot be replayed against another ranking.
+func encodePostCursor(sort models.FeedSort, c models.PostCursor) string {
+	return encodeCursor(append([]string{string(sort)}, postCursorParts(c)...)...)
+}
+
+// decodePostCursor parses a cursor produced by encodePostCursor for the same sort mode
+func decodePostCursor(sort models.FeedSort, cursor string) (*models.PostCursor, error) {
+	parts, err := decodeCursor(cursor, 4)
+	if err != nil || parts[0] != string(sort) {
+		return nil, entities.ErrInvalidCursor
+	}
+	return parsePostCursor(parts[1:])
+}
+
+// encodeSearchCursor turns a search position into an opaque string for
+// clients. It carries the time the search was ranked at, so later pages
+// rank with the same scores as the first.
+func encodeSearchCursor(rankedAt time.Time, c models.PostCursor) string {
+	return encodeCursor(append([]string{searchCursorScope, rankedAt.UTC().Format(time.RFC3339Nano)}, postCursorParts(c)...)...)
+}
+
+// decodeSearchCursor parses a cursor produced by encodeSearchCursor
+func decodeSearchCursor(cursor string) (time.Time, *models.PostCursor, error) {
+	parts, err := decodeCursor(cursor, 5)
+	if err != nil || parts[0] != searchCursorScope {
+		return time.Time{}, nil, entities.ErrInvalidCursor
+	}
+
+	rankedAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return time.Time{}, nil, entities.ErrInvalidCursor
+	}
+
+	after, err := parsePostCursor(parts[2:])
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return rankedAt, after, nil
+}
+
+// encodeCursor joins the parts of a position into an opaque string
+func encodeCursor(parts ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, "|")))
+}
+
+// decodeCursor splits a cursor produced by encodeCursor, which must have n parts
+func decodeCursor(cursor string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != n {
+		return nil, entities.ErrInvalidCursor
+	}
+	return parts, nil
+}
+
+// postCursorParts formats the score, creation time and id of a post position
+func postCursorParts(c models.PostCursor) []string {
+	return []string{
+		strconv.FormatFloat(c.Score, 'g', -1, 64),
+		c.CreatedAt.UTC().Format(time.RFC3339Nano),
+		c.ID.String(),
+	}
+}
+
+// parsePostCursor parses the parts produced by postCursorParts
+func parsePostCursor(parts []string) (*models.PostCursor, error) {
+	score, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, entities.ErrInvalidCursor
+	}
+
+	return &models.PostCursor{Score: score, CreatedAt: createdAt, ID: id}, nil
+}
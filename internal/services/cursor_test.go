@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	rankedAt := time.Date(2026, 10, 17, 12, 30, 0, 123456789, time.UTC)
+	position := models.PostCursor{
+		Score:     0.734215,
+		CreatedAt: time.Date(2026, 10, 16, 8, 0, 0, 5, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	gotRankedAt, got, err := decodeSearchCursor(encodeSearchCursor(rankedAt, position))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !gotRankedAt.Equal(rankedAt) {
+		t.Errorf("ranked at %v, want %v", gotRankedAt, rankedAt)
+	}
+	if got.Score != position.Score || !got.CreatedAt.Equal(position.CreatedAt) || got.ID != position.ID {
+		t.Errorf("position %+v, want %+v", *got, position)
+	}
+}
+
+func TestCursorsDoNotCrossRankings(t *testing.T) {
+	position := models.PostCursor{Score: 1, CreatedAt: time.Now(), ID: uuid.New()}
+
+	if _, _, err := decodeSearchCursor(encodePostCursor(models.SortHot, position)); !errors.Is(err, entities.ErrInvalidCursor) {
+		t.Errorf("feed cursor accepted by search: %v", err)
+	}
+	if _, err := decodePostCursor(models.SortHot, encodeSearchCursor(time.Now(), position)); !errors.Is(err, entities.ErrInvalidCursor) {
+		t.Errorf("search cursor accepted by feed: %v", err)
+	}
+	if _, err := decodePostCursor(models.SortTop, encodePostCursor(models.SortHot, position)); !errors.Is(err, entities.ErrInvalidCursor) {
+		t.Errorf("hot cursor accepted by top: %v", err)
+	}
+	if _, _, err := decodeSearchCursor("not a cursor!"); !errors.Is(err, entities.ErrInvalidCursor) {
+		t.Errorf("garbage accepted: %v", err)
+	}
+}
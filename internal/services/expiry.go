@@ -0,0 +1,78 @@
+package services
+
+import (
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"time"
+)
+
+const (
+	// MinPostLifetime and MaxPostLifetime bound the expires_in a client may ask for
+	MinPostLifetime = time.Hour
+	MaxPostLifetime = 90 * 24 * time.Hour
+
+	// DefaultExpirySweepInterval is how often expired posts are purged
+	DefaultExpirySweepInterval = 15 * time.Minute
+	// DefaultExpiredPostRetention is how long expired posts stay available to
+	// moderators before they are purged
+	DefaultExpiredPostRetention = 7 * 24 * time.Hour
+
+	// purgeBatchSize is how many posts are purged per transaction
+	purgeBatchSize = 100
+)
+
+// categoryLifetimes is how long posts live when the author does not choose.
+// Recommendations stay useful and do not expire by default.
+var categoryLifetimes = map[enums.Category]time.Duration{
+	enums.CategoryGeneral:      30 * 24 * time.Hour,
+	enums.CategoryLostAndFound: 14 * 24 * time.Hour,
+	enums.CategorySafetyAlert:  48 * time.Hour,
+	enums.CategoryEvent:        7 * 24 * time.Hour,
+	enums.CategoryForSale:      30 * 24 * time.Hour,
+}
+
+// postExpiry returns when a new post expires, given the lifetime requested in
+// seconds or nil for the category default. Requested lifetimes must lie
+// between MinPostLifetime and MaxPostLifetime.
+func postExpiry(category enums.Category, expiresIn *int64, now time.Time) (*time.Time, error) {
+	lifetime, ok := categoryLifetimes[category]
+	if expiresIn != nil {
+		// Checked in seconds first so a huge value cannot overflow the Duration
+		if *expiresIn < int64(MinPostLifetime/time.Second) || *expiresIn > int64(MaxPostLifetime/time.Second) {
+			return nil, entities.ErrInvalidExpiry
+		}
+		lifetime, ok = time.Duration(*expiresIn)*time.Second, true
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	expiresAt := now.Add(lifetime)
+	return &expiresAt, nil
+}
+
+// isExpired reports whether a post has passed its expiry
+func isExpired(post *entities.Post) bool {
+	return post.ExpiresAt != nil && !post.ExpiresAt.After(time.Now())
+}
+
+// PurgeExpiredPosts permanently deletes posts that expired longer ago than
+// the retention period, with their comments, votes, reports and images
+func (s *service) PurgeExpiredPosts() (int64, error) {
+	before := time.Now().Add(-s.config.ExpiredPostRetention)
+
+	var total int64
+	for {
+		images, purged, err := s.model.PurgeExpiredPosts(before, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		s.deletePostImages(images)
+		total += purged
+
+		if purged < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
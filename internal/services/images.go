@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hyperlocal/internal/entities"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	// Decoders for the accepted upload formats
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/draw"
+)
+
+const (
+	// DefaultMaxPostImages is how many images a post may carry
+	DefaultMaxPostImages = 4
+	// DefaultMaxImageBytes is the largest accepted upload per image
+	DefaultMaxImageBytes = 5 << 20
+
+	// maxImagePixels rejects images that would take too much memory to decode
+	maxImagePixels = 40_000_000
+	// maxImageSide and thumbnailSide bound the longest side of stored images
+	maxImageSide  = 2048
+	thumbnailSide = 320
+	jpegQuality   = 85
+)
+
+// ImageUpload is an image attached to a new post
+type ImageUpload struct {
+	Filename string
+	Reader   io.Reader
+}
+
+// ImageResponse represents an image attached to a post
+type ImageResponse struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// processedImage is an upload re-encoded for storage
+type processedImage struct {
+	contentType string
+	ext         string
+	data        []byte
+	thumbnail   []byte
+	width       int
+	height      int
+}
+
+// processImage validates an upload by its sniffed content type and size, and
+// re-encodes it at a bounded size along with a thumbnail. Re-encoding keeps
+// only pixels, so EXIF (including GPS position), XMP and any other metadata
+// are dropped. The EXIF orientation is applied first so photos stay upright.
+func processImage(upload ImageUpload, maxBytes int64) (*processedImage, error) {
+	data, err := io.ReadAll(io.LimitReader(upload.Reader, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, entities.ErrImageTooLarge
+	}
+
+	// The declared type and file name are ignored, only the bytes count
+	sniffed := http.DetectContentType(data)
+	switch sniffed {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return nil, entities.ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, entities.ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, entities.ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, entities.ErrUnsupportedImage
+	}
+
+	orientation := 1
+	if sniffed == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+
+	full := orient(fit(img, maxImageSide), orientation)
+	thumb := orient(fit(img, thumbnailSide), orientation)
+
+	// PNG and GIF may be transparent, so they are stored as PNG
+	processed := &processedImage{contentType: "image/jpeg", ext: ".jpg"}
+	encode := func(w io.Writer, m image.Image) error {
+		return jpeg.Encode(w, m, &jpeg.Options{Quality: jpegQuality})
+	}
+	if sniffed == "image/png" || sniffed == "image/gif" {
+		processed.contentType, processed.ext = "image/png", ".png"
+		encode = png.Encode
+	}
+
+	var fullBuf, thumbBuf bytes.Buffer
+	if err := encode(&fullBuf, full); err != nil {
+		return nil, err
+	}
+	if err := encode(&thumbBuf, thumb); err != nil {
+		return nil, err
+	}
+
+	processed.data = fullBuf.Bytes()
+	processed.thumbnail = thumbBuf.Bytes()
+	processed.width = full.Bounds().Dx()
+	processed.height = full.Bounds().Dy()
+	return processed, nil
+}
+
+// fit scales img down so its longest side is at most side
+func fit(img image.Image, side int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= side && h <= side {
+		return img
+	}
+
+	if w >= h {
+		h = max(1, h*side/w)
+		w = side
+	} else {
+		w = max(1, w*side/h)
+		h = side
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// orient applies an EXIF orientation (1-8) to img
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, or returns 1
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the marker segments up to the start of the image data
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA {
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads tag 0x0112 from the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[offset:]))
+	for e := 0; e < entries; e++ {
+		entry := offset + 2 + e*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
+
+// storePostImages processes and stores the uploads of a new post, returning
+// the rows to save with it. On error nothing is left in the blob store.
+func (s *service) storePostImages(uploads []ImageUpload) ([]entities.PostImage, error) {
+	if len(uploads) > s.config.MaxPostImages {
+		return nil, entities.ErrTooManyImages
+	}
+
+	// Validate every upload before storing any of them
+	processed := make([]*processedImage, len(uploads))
+	for i, upload := range uploads {
+		p, err := processImage(upload, s.config.MaxImageBytes)
+		if err != nil {
+			return nil, err
+		}
+		processed[i] = p
+	}
+
+	ctx := context.Background()
+	images := make([]entities.PostImage, 0, len(processed))
+	for i, p := range processed {
+		id := uuid.New()
+		image := entities.PostImage{
+			ID:           id,
+			Position:     i,
+			Key:          "images/" + id.String() + p.ext,
+			ThumbnailKey: "images/" + id.String() + "_thumb" + p.ext,
+			ContentType:  p.contentType,
+			Width:        p.width,
+			Height:       p.height,
+			CreatedAt:    time.Now(),
+		}
+
+		err := s.blobs.Put(ctx, image.Key, bytes.NewReader(p.data), p.contentType)
+		if err == nil {
+			err = s.blobs.Put(ctx, image.ThumbnailKey, bytes.NewReader(p.thumbnail), p.contentType)
+		}
+		if err != nil {
+			s.deletePostImages(append(images, image))
+			return nil, err
+		}
+
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// deletePostImages removes stored images, logging failures since the caller
+// is already handling another error
+func (s *service) deletePostImages(images []entities.PostImage) {
+	ctx := context.Background()
+	for _, image := range images {
+		for _, key := range []string{image.Key, image.ThumbnailKey} {
+			if err := s.blobs.Delete(ctx, key); err != nil {
+				log.Printf("Failed to delete blob %s: %v", key, err)
+			}
+		}
+	}
+}
+
+// ImageVisible reports whether the image or thumbnail stored under key may be
+// served to anyone. Images of deleted and expired posts are kept for
+// moderators until the post is purged, but are no longer public.
+func (s *service) ImageVisible(key string) (bool, error) {
+	return s.model.IsImageVisible(key)
+}
+
+// newImageResponses converts stored images into their public URLs
+func (s *service) newImageResponses(images []entities.PostImage) []ImageResponse {
+	if len(images) == 0 {
+		return nil
+	}
+
+	responses := make([]ImageResponse, len(images))
+	for i, image := range images {
+		responses[i] = ImageResponse{
+			URL:          s.blobs.URL(image.Key),
+			ThumbnailURL: s.blobs.URL(image.ThumbnailKey),
+			Width:        image.Width,
+			Height:       image.Height,
+		}
+	}
+	return responses
+}
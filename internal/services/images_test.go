@@ -0,0 +1,360 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"hyperlocal/internal/entities"
+)
+
+// gpsMarker is written into the GPS IFD of the test EXIF block so any leak
+// of it is easy to spot
+const gpsMarker = "HYPERLOCAL-GPS-MARKER"
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halves returns a w x h image whose left half is red and right half blue
+func halves(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// exifSegment builds an APP1 segment with a big-endian TIFF structure holding
+// an orientation tag and a GPS IFD with a latitude and a map datum
+func exifSegment(orientation uint16) []byte {
+	order := binary.BigEndian
+	tiff := []byte("MM\x00\x2a")
+	tiff = order.AppendUint32(tiff, 8)
+
+	// IFD0: orientation and a pointer to the GPS IFD
+	const ifd0Entries = 2
+	gpsOffset := uint32(8 + 2 + ifd0Entries*12 + 4)
+	tiff = order.AppendUint16(tiff, ifd0Entries)
+	tiff = appendIFDEntry(tiff, 0x0112, 3, 1, uint32(orientation)<<16)
+	tiff = appendIFDEntry(tiff, 0x8825, 4, 1, gpsOffset)
+	tiff = order.AppendUint32(tiff, 0)
+
+	// GPS IFD: latitude ref inline, the marker as the map datum after the IFD
+	const gpsEntries = 2
+	datum := gpsMarker + "\x00"
+	datumOffset := gpsOffset + 2 + gpsEntries*12 + 4
+	tiff = order.AppendUint16(tiff, gpsEntries)
+	tiff = appendIFDEntry(tiff, 0x0001, 2, 2, uint32('N')<<24)
+	tiff = appendIFDEntry(tiff, 0x0012, 2, uint32(len(datum)), datumOffset)
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, datum...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = order.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func appendIFDEntry(b []byte, tag, typ uint16, count, value uint32) []byte {
+	b = binary.BigEndian.AppendUint16(b, tag)
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint32(b, count)
+	return binary.BigEndian.AppendUint32(b, value)
+}
+
+// withEXIF inserts an EXIF segment right after the SOI marker of a JPEG
+func withEXIF(jpegData []byte, orientation uint16) []byte {
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, exifSegment(orientation)...)
+	return append(out, jpegData[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xC000 && g < 0x4000 && b < 0x4000
+}
+
+func isBlue(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return b > 0xC000 && r < 0x4000 && g < 0x4000
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return img
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, halves(8, 4))
+
+	if got := jpegOrientation(plain); got != 1 {
+		t.Errorf("orientation without EXIF = %d, want 1", got)
+	}
+	for _, orientation := range []uint16{1, 3, 6, 8} {
+		if got := jpegOrientation(withEXIF(plain, orientation)); got != int(orientation) {
+			t.Errorf("orientation = %d, want %d", got, orientation)
+		}
+	}
+	if got := jpegOrientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("orientation of garbage = %d, want 1", got)
+	}
+}
+
+func TestProcessImageStripsEXIF(t *testing.T) {
+	upload := withEXIF(encodeJPEG(t, halves(40, 20)), 6)
+	if !bytes.Contains(upload, []byte(gpsMarker)) {
+		t.Fatal("test upload is missing its GPS data")
+	}
+
+	processed, err := processImage(ImageUpload{Filename: "photo.jpg", Reader: bytes.NewReader(upload)}, DefaultMaxImageBytes)
+	if err != nil {
+		t.Fatalf("processImage: %v", err)
+	}
+
+	for name, data := range map[string][]byte{"image": processed.data, "thumbnail": processed.thumbnail} {
+		if bytes.Contains(data, []byte("Exif")) {
+			t.Errorf("%s still has an EXIF segment", name)
+		}
+		if bytes.Contains(data, []byte(gpsMarker)) {
+			t.Errorf("%s still has the GPS data", name)
+		}
+	}
+
+	if processed.contentType != "image/jpeg" || processed.ext != ".jpg" {
+		t.Errorf("stored as %s (%s), want image/jpeg (.jpg)", processed.contentType, processed.ext)
+	}
+
+	// Orientation 6 turns the 40x20 image upright, moving the red left half
+	// to the top
+	if processed.width != 20 || processed.height != 40 {
+		t.Errorf("size %dx%d, want 20x40", processed.width, processed.height)
+	}
+	img := decode(t, processed.data)
+	if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+		t.Errorf("stored image is %dx%d, want 20x40", b.Dx(), b.Dy())
+	}
+	if !isRed(img.At(10, 5)) || !isBlue(img.At(10, 35)) {
+		t.Errorf("image not rotated: top %v, bottom %v", img.At(10, 5), img.At(10, 35))
+	}
+}
+
+func TestProcessImageBoundsSize(t *testing.T) {
+	upload := encodePNG(t, halves(3000, 1000))
+
+	processed, err := processImage(ImageUpload{Reader: bytes.NewReader(upload)}, DefaultMaxImageBytes)
+	if err != nil {
+		t.Fatalf("processImage: %v", err)
+	}
+
+	if processed.contentType != "image/png" || processed.ext != ".png" {
+		t.Errorf("stored as %s (%s), want image/png (.png)", processed.contentType, processed.ext)
+	}
+	if processed.width != maxImageSide || processed.height != 682 {
+		t.Errorf("size %dx%d, want %dx682", processed.width, processed.height, maxImageSide)
+	}
+	if b := decode(t, processed.thumbnail).Bounds(); b.Dx() != thumbnailSide || b.Dy() != 106 {
+		t.Errorf("thumbnail is %dx%d, want %dx106", b.Dx(), b.Dy(), thumbnailSide)
+	}
+}
+
+// withPNGSize rewrites the dimensions in a PNG header, leaving the pixel data
+// as it was
+func withPNGSize(data []byte, w, h uint32) []byte {
+	out := append([]byte{}, data...)
+	// Signature (8), IHDR length (4) and type (4), then width and height
+	binary.BigEndian.PutUint32(out[16:], w)
+	binary.BigEndian.PutUint32(out[20:], h)
+	binary.BigEndian.PutUint32(out[29:], crc32.ChecksumIEEE(out[12:29]))
+	return out
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	small := encodeJPEG(t, halves(8, 8))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		maxBytes int64
+		want     error
+	}{
+		{"text", []byte("definitely not an image"), DefaultMaxImageBytes, entities.ErrUnsupportedImage},
+		{"html", []byte("<html><body>hi</body></html>"), DefaultMaxImageBytes, entities.ErrUnsupportedImage},
+		{"truncated jpeg", small[:len(small)/2], DefaultMaxImageBytes, entities.ErrUnsupportedImage},
+		{"too many bytes", small, int64(len(small) - 1), entities.ErrImageTooLarge},
+		{"too many pixels", withPNGSize(encodePNG(t, halves(8, 8)), 8000, 8000), DefaultMaxImageBytes, entities.ErrImageTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := processImage(ImageUpload{Filename: "photo.jpg", Reader: bytes.NewReader(tt.data)}, tt.maxBytes)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// fakeBlobStore keeps blobs in memory and can fail a given Put
+type fakeBlobStore struct {
+	blobs  map[string][]byte
+	puts   int
+	failAt int
+}
+
+func newFakeBlobStore() *fakeBlobStore {
+	return &fakeBlobStore{blobs: map[string][]byte{}}
+}
+
+func (f *fakeBlobStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	f.puts++
+	if f.puts == f.failAt {
+		return errors.New("disk full")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	f.blobs[key] = data
+	return nil
+}
+
+func (f *fakeBlobStore) Delete(ctx context.Context, key string) error {
+	delete(f.blobs, key)
+	return nil
+}
+
+func (f *fakeBlobStore) URL(key string) string {
+	return "/media/" + key
+}
+
+func newImageService(blobs *fakeBlobStore) *service {
+	return &service{
+		blobs:  blobs,
+		config: Config{MaxPostImages: 2, MaxImageBytes: DefaultMaxImageBytes},
+	}
+}
+
+func jpegUploads(t *testing.T, n int) []ImageUpload {
+	t.Helper()
+
+	uploads := make([]ImageUpload, n)
+	for i := range uploads {
+		uploads[i] = ImageUpload{Reader: bytes.NewReader(encodeJPEG(t, halves(16, 8)))}
+	}
+	return uploads
+}
+
+func TestStorePostImages(t *testing.T) {
+	blobs := newFakeBlobStore()
+	s := newImageService(blobs)
+
+	images, err := s.storePostImages(jpegUploads(t, 2))
+	if err != nil {
+		t.Fatalf("storePostImages: %v", err)
+	}
+
+	if len(images) != 2 || len(blobs.blobs) != 4 {
+		t.Fatalf("stored %d images in %d blobs, want 2 in 4", len(images), len(blobs.blobs))
+	}
+	for i, image := range images {
+		if image.Position != i {
+			t.Errorf("image %d at position %d", i, image.Position)
+		}
+		if !strings.HasPrefix(image.Key, "images/") || !strings.HasSuffix(image.ThumbnailKey, "_thumb.jpg") {
+			t.Errorf("unexpected keys %q and %q", image.Key, image.ThumbnailKey)
+		}
+		if _, ok := blobs.blobs[image.Key]; !ok {
+			t.Errorf("image %s not stored", image.Key)
+		}
+		if _, ok := blobs.blobs[image.ThumbnailKey]; !ok {
+			t.Errorf("thumbnail %s not stored", image.ThumbnailKey)
+		}
+		if image.Width != 16 || image.Height != 8 {
+			t.Errorf("image %d is %dx%d, want 16x8", i, image.Width, image.Height)
+		}
+	}
+
+	responses := s.newImageResponses(images)
+	if responses[0].URL != "/media/"+images[0].Key || responses[0].ThumbnailURL != "/media/"+images[0].ThumbnailKey {
+		t.Errorf("unexpected urls %+v", responses[0])
+	}
+}
+
+func TestStorePostImagesStoresNothingOnError(t *testing.T) {
+	t.Run("too many images", func(t *testing.T) {
+		blobs := newFakeBlobStore()
+		_, err := newImageService(blobs).storePostImages(jpegUploads(t, 3))
+		if !errors.Is(err, entities.ErrTooManyImages) {
+			t.Errorf("err = %v, want %v", err, entities.ErrTooManyImages)
+		}
+		if blobs.puts != 0 {
+			t.Errorf("%d blobs stored", blobs.puts)
+		}
+	})
+
+	t.Run("invalid upload", func(t *testing.T) {
+		blobs := newFakeBlobStore()
+		uploads := append(jpegUploads(t, 1), ImageUpload{Reader: strings.NewReader("not an image")})
+		_, err := newImageService(blobs).storePostImages(uploads)
+		if !errors.Is(err, entities.ErrUnsupportedImage) {
+			t.Errorf("err = %v, want %v", err, entities.ErrUnsupportedImage)
+		}
+		if blobs.puts != 0 {
+			t.Errorf("%d blobs stored before every upload was validated", blobs.puts)
+		}
+	})
+
+	t.Run("store failure", func(t *testing.T) {
+		blobs := newFakeBlobStore()
+		blobs.failAt = 4
+		if _, err := newImageService(blobs).storePostImages(jpegUploads(t, 2)); err == nil {
+			t.Fatal("expected the store failure")
+		}
+		if len(blobs.blobs) != 0 {
+			t.Errorf("%d blobs left behind", len(blobs.blobs))
+		}
+	})
+}
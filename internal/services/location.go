@@ -0,0 +1,92 @@
+package services
+
+import (
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"math"
+)
+
+const (
+	// DefaultLocationPrecision is used when a post does not ask for a precision
+	DefaultLocationPrecision = enums.PrecisionStreet
+
+	// coarsenBatchSize is how many posts CoarsenPostLocations loads at a time
+	coarsenBatchSize = 100
+)
+
+// metersPerDegree is the length of one degree of latitude
+const metersPerDegree = 111_320
+
+// precisionCellMeters is the size of the grid cells coordinates are snapped
+// to at each precision. Exact locations are stored as given.
+var precisionCellMeters = map[enums.LocationPrecision]float64{
+	enums.PrecisionStreet:        100,
+	enums.PrecisionNeighbourhood: 1000,
+}
+
+// fuzzLocation snaps a location to the centre of its grid cell at the given
+// precision. Snapping is deterministic: every post made from the same place
+// lands on the same point, so unlike random jitter, many posts from one home
+// cannot be averaged back to it.
+func fuzzLocation(lat, lng float64, precision enums.LocationPrecision) (float64, float64) {
+	cell := precisionCellMeters[precision]
+	if cell == 0 {
+		return lat, lng
+	}
+
+	latStep := cell / metersPerDegree
+	lat = math.Max(-90, math.Min(90, (math.Floor(lat/latStep)+0.5)*latStep))
+
+	// Degrees of longitude shrink towards the poles, so the cells of a row are
+	// sized at its snapped latitude
+	lngStep := latStep / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	lng = (math.Floor(lng/lngStep) + 0.5) * lngStep
+	if lng > 180 {
+		lng -= 360
+	} else if lng < -180 {
+		lng += 360
+	}
+
+	return lat, lng
+}
+
+// approximateDistance rounds the distance to a post to the precision of its
+// location, so a fuzzed post is not shown as a few metres away
+func approximateDistance(post *entities.Post) float64 {
+	step := precisionCellMeters[post.LocationPrecision]
+	if step == 0 {
+		step = 1
+	}
+	return math.Round(post.DistanceMeters/step) * step
+}
+
+// CoarsenPostLocations snaps every post stored more precisely than
+// MinLocationPrecision to it, so raising the minimum also covers posts made
+// before. It returns how many posts were moved.
+func (s *service) CoarsenPostLocations() (int64, error) {
+	finer := s.config.MinLocationPrecision.Finer()
+	if len(finer) == 0 {
+		return 0, nil
+	}
+
+	var total int64
+	for {
+		// Coarsened posts stop matching, so each batch starts from the top
+		posts, err := s.model.GetPostsWithPrecision(finer, coarsenBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		for _, post := range posts {
+			latitude, longitude := fuzzLocation(post.Latitude, post.Longitude, s.config.MinLocationPrecision)
+			if err := s.model.UpdatePostLocation(post.ID, latitude, longitude, s.config.MinLocationPrecision); err != nil {
+				return total, err
+			}
+			total++
+		}
+
+		if len(posts) < coarsenBatchSize {
+			return total, nil
+		}
+	}
+}
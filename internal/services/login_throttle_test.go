@@ -0,0 +1,240 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"hyperlocal/internal/entities"
+)
+
+// fakeThrottleStore keeps login throttles in memory with the same counting
+// rules as the login_throttles upsert
+type fakeThrottleStore struct {
+	throttles map[string]*entities.LoginThrottle
+}
+
+func newFakeThrottleStore() *fakeThrottleStore {
+	return &fakeThrottleStore{throttles: map[string]*entities.LoginThrottle{}}
+}
+
+func (f *fakeThrottleStore) GetLoginThrottle(key string) (*entities.LoginThrottle, error) {
+	if throttle, ok := f.throttles[key]; ok {
+		copied := *throttle
+		return &copied, nil
+	}
+	return &entities.LoginThrottle{Key: key}, nil
+}
+
+func (f *fakeThrottleStore) RecordLoginFailure(key string, now time.Time, lockAfter int, lockout, resetAfter time.Duration) (*entities.LoginThrottle, error) {
+	throttle, ok := f.throttles[key]
+	if !ok || throttle.LastFailureAt.Before(now.Add(-resetAfter)) {
+		throttle = &entities.LoginThrottle{Key: key, LockedUntil: lockedUntil(throttle)}
+		f.throttles[key] = throttle
+	}
+
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	if throttle.Failures >= lockAfter {
+		until := now.Add(lockout)
+		throttle.LockedUntil = &until
+	}
+
+	copied := *throttle
+	return &copied, nil
+}
+
+func (f *fakeThrottleStore) ClearLoginThrottle(keys ...string) error {
+	for _, key := range keys {
+		delete(f.throttles, key)
+	}
+	return nil
+}
+
+func lockedUntil(throttle *entities.LoginThrottle) *time.Time {
+	if throttle == nil {
+		return nil
+	}
+	return throttle.LockedUntil
+}
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestThrottle() (loginThrottle, *fakeThrottleStore, *fakeClock) {
+	store := newFakeThrottleStore()
+	clock := &fakeClock{now: time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)}
+	return loginThrottle{store: store, now: clock.Now}, store, clock
+}
+
+func throttled(t *testing.T, err error) *entities.LoginThrottledError {
+	t.Helper()
+
+	var throttledErr *entities.LoginThrottledError
+	if !errors.As(err, &throttledErr) {
+		t.Fatalf("err = %v, want a LoginThrottledError", err)
+	}
+	if !errors.Is(err, entities.ErrLoginThrottled) {
+		t.Errorf("err does not unwrap to ErrLoginThrottled")
+	}
+	return throttledErr
+}
+
+func TestLoginBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{loginFreeAttempts - 1, 0},
+		{loginFreeAttempts, 2 * time.Second},
+		{loginFreeAttempts + 1, 4 * time.Second},
+		{loginFreeAttempts + 2, 8 * time.Second},
+		{loginFreeAttempts + 7, 256 * time.Second},
+		{loginFreeAttempts + 8, loginMaxBackoff},
+		{1000, loginMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := loginBackoff(tt.failures); got != tt.want {
+			t.Errorf("loginBackoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginThrottleBacksOff(t *testing.T) {
+	throttle, _, clock := newTestThrottle()
+	keys := throttleKeys("alice", "203.0.113.7")
+
+	for i := 0; i < loginFreeAttempts; i++ {
+		if err := throttle.check(keys...); err != nil {
+			t.Fatalf("attempt %d throttled: %v", i+1, err)
+		}
+		throttle.recordFailure("alice", "203.0.113.7")
+	}
+
+	err := throttled(t, throttle.check(keys...))
+	if err.AccountLocked {
+		t.Error("backoff reported as an account lock")
+	}
+	if err.RetryAfter != loginBackoffBase {
+		t.Errorf("retry after %s, want %s", err.RetryAfter, loginBackoffBase)
+	}
+
+	clock.Advance(loginBackoffBase - time.Millisecond)
+	throttled(t, throttle.check(keys...))
+
+	clock.Advance(time.Millisecond)
+	if err := throttle.check(keys...); err != nil {
+		t.Fatalf("still throttled once the backoff passed: %v", err)
+	}
+}
+
+func TestLoginThrottleLocksAccount(t *testing.T) {
+	throttle, _, clock := newTestThrottle()
+
+	// Each attempt comes from a fresh IP after waiting out the backoff, so only
+	// the username count can stop it
+	for i := 0; i < LoginLockAfter; i++ {
+		clock.Advance(loginMaxBackoff)
+		if err := throttle.check(userThrottleKey("alice")); err != nil {
+			t.Fatalf("attempt %d throttled: %v", i+1, err)
+		}
+		throttle.recordFailure("alice", "")
+	}
+
+	err := throttled(t, throttle.check(throttleKeys("Alice", "198.51.100.1")...))
+	if !err.AccountLocked {
+		t.Error("lockout not reported as an account lock")
+	}
+	if err.RetryAfter != LoginLockoutDuration {
+		t.Errorf("retry after %s, want %s", err.RetryAfter, LoginLockoutDuration)
+	}
+
+	clock.Advance(LoginLockoutDuration)
+	if err := throttle.check(userThrottleKey("alice")); err != nil {
+		t.Fatalf("still locked after the lockout: %v", err)
+	}
+}
+
+func TestLoginThrottleClearLiftsLockout(t *testing.T) {
+	throttle, _, _ := newTestThrottle()
+
+	for i := 0; i < LoginLockAfter; i++ {
+		throttle.recordFailure("alice", "")
+	}
+	throttled(t, throttle.check(userThrottleKey("alice")))
+
+	if err := throttle.clear("ALICE"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if err := throttle.check(userThrottleKey("alice")); err != nil {
+		t.Fatalf("still locked after clearing: %v", err)
+	}
+}
+
+func TestLoginThrottleNeverLocksIP(t *testing.T) {
+	throttle, store, _ := newTestThrottle()
+
+	for i := 0; i < 3*LoginLockAfter; i++ {
+		throttle.recordFailure("", "203.0.113.7")
+	}
+
+	if state := store.throttles[ipThrottleKey("203.0.113.7")]; state.LockedUntil != nil {
+		t.Errorf("IP locked until %s", state.LockedUntil)
+	}
+
+	err := throttled(t, throttle.check(ipThrottleKey("203.0.113.7")))
+	if err.AccountLocked {
+		t.Error("IP backoff reported as an account lock")
+	}
+	if err.RetryAfter != loginMaxBackoff {
+		t.Errorf("retry after %s, want %s", err.RetryAfter, loginMaxBackoff)
+	}
+}
+
+func TestLoginThrottleForgetsOldFailures(t *testing.T) {
+	throttle, store, clock := newTestThrottle()
+
+	for i := 0; i < LoginLockAfter-1; i++ {
+		throttle.recordFailure("alice", "")
+	}
+
+	clock.Advance(loginFailureWindow + time.Second)
+	if err := throttle.check(userThrottleKey("alice")); err != nil {
+		t.Fatalf("old failures still throttle: %v", err)
+	}
+
+	throttle.recordFailure("alice", "")
+	state := store.throttles[userThrottleKey("alice")]
+	if state.Failures != 1 {
+		t.Errorf("failures = %d after the window, want 1", state.Failures)
+	}
+	if state.LockedUntil != nil {
+		t.Errorf("locked until %s by failures outside the window", state.LockedUntil)
+	}
+}
+
+func TestIPThrottleKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"203.0.113.7", "203.0.113.7", true},
+		{"203.0.113.7", "203.0.113.8", false},
+		{"2001:db8::1", "2001:db8::ffff:1", true},
+		{"2001:db8::1", "2001:db8:0:1::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := ipThrottleKey(tt.a) == ipThrottleKey(tt.b); got != tt.same {
+			t.Errorf("%s and %s share a key: %v, want %v", tt.a, tt.b, got, tt.same)
+		}
+	}
+}
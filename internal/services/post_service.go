@@ -1,6 +1,11 @@
 package services
 
 import (
+	"fmt"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"hyperlocal/internal/models"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,30 +13,253 @@ import (
 
 // CreatePostRequest represents the request body for creating a post
 type CreatePostRequest struct {
-	Content   string  `json:"content" validate:"required,min=1,max=500"`
+	Content string `json:"content" validate:"required,min=1,max=500"`
+	// Category is one of the curated categories listed by GET /categories
+	Category string `json:"category" validate:"required"`
+	// Language is the text search configuration to stem the post with, one of
+	// SearchLanguages; empty means the server default
+	Language  string  `json:"language,omitempty"`
 	Latitude  float64 `json:"latitude" validate:"required"`
 	Longitude float64 `json:"longitude" validate:"required"`
+	// Anonymous hides the author from other users behind a per-post pseudonym
+	Anonymous bool `json:"anonymous,omitempty"`
+	// ExpiresIn is the post's lifetime in seconds, between MinPostLifetime and
+	// MaxPostLifetime; without it the category default applies, and
+	// recommendations do not expire
+	ExpiresIn *int64 `json:"expires_in,omitempty"`
+	// LocationPrecision is how precisely the location is stored: exact, street
+	// (default) or neighbourhood. The server may enforce a coarser minimum.
+	LocationPrecision string `json:"location_precision,omitempty" validate:"omitempty,oneof=exact street neighbourhood"`
+	// Images are read from a multipart upload rather than the JSON body
+	Images []ImageUpload `json:"-" swaggerignore:"true"`
 }
 
 // PostResponse represents the response for a post
 type PostResponse struct {
 	ID        string    `json:"id"`
 	Content   string    `json:"content"`
+	Category  string    `json:"category"`
+	Tags      []string  `json:"tags"`
 	Username  *string   `json:"username"`
 	Upvotes   int       `json:"upvotes"`
 	Downvotes int       `json:"downvotes"`
 	CreatedAt time.Time `json:"created_at"`
 	IsFlagged bool      `json:"is_flagged,omitempty"`
+	// Pseudonym replaces Username on anonymous posts, such as "Neighbour #1"
+	Pseudonym string `json:"pseudonym,omitempty"`
+	// AuthorID is only set in moderator views, which also show the username
+	// of anonymous authors
+	AuthorID string `json:"author_id,omitempty"`
+	// EditedAt is set once the author has edited the post
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// ExpiresAt is when the post stops being shown, unset if it never does
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// LocationPrecision is how precisely the post's location is stored
+	LocationPrecision string `json:"location_precision"`
+	// Images are the attachments of the post in upload order
+	Images []ImageResponse `json:"images,omitempty"`
+	// DistanceMeters is the distance from the caller, set on feed results
+	DistanceMeters *float64 `json:"distance_meters,omitempty"`
+	// MyVote is the caller's vote on the post: up, down or none
+	MyVote string `json:"my_vote,omitempty"`
+	// Deletion is only set in moderator views of removed posts
+	Deletion *DeletionResponse `json:"deletion,omitempty"`
+}
+
+// Values of PostResponse.MyVote
+const (
+	MyVoteUp   = "up"
+	MyVoteDown = "down"
+	MyVoteNone = "none"
+)
+
+// setMyVotes fills in MyVote on responses for the given user, loading every
+// vote in one query. responses[i] must describe posts[i].
+func (s *service) setMyVotes(userID uuid.UUID, posts []entities.Post, responses []PostResponse) error {
+	postIDs := make([]uuid.UUID, len(posts))
+	for i := range posts {
+		postIDs[i] = posts[i].ID
+	}
+
+	votes, err := s.model.GetUserPostVotes(userID, postIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		switch votes[posts[i].ID] {
+		case enums.VoteUp:
+			responses[i].MyVote = MyVoteUp
+		case enums.VoteDown:
+			responses[i].MyVote = MyVoteDown
+		default:
+			responses[i].MyVote = MyVoteNone
+		}
+	}
+	return nil
+}
+
+// DeleteRequest represents the optional request body for deleting a post or comment
+type DeleteRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// DeletionResponse describes who removed a post or comment and why
+type DeletionResponse struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// newDeletionResponse returns nil unless the content has been deleted
+func newDeletionResponse(deletedAt *time.Time, deletedBy *uuid.UUID, reason string) *DeletionResponse {
+	if deletedAt == nil {
+		return nil
+	}
+
+	deletion := &DeletionResponse{DeletedAt: *deletedAt, Reason: reason}
+	if deletedBy != nil {
+		deletion.DeletedBy = deletedBy.String()
+	}
+	return deletion
+}
+
+// EditPostRequest represents the request body for editing a post
+type EditPostRequest struct {
+	Content string `json:"content" validate:"required,min=1,max=500"`
+}
+
+// PostRevisionResponse represents an earlier version of a post
+type PostRevisionResponse struct {
+	ID       string    `json:"id"`
+	Content  string    `json:"content"`
+	EditedBy string    `json:"edited_by"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+// newPostResponse converts a post with its user loaded into the response
+// format, hiding the author of an anonymous post
+func (s *service) newPostResponse(post *entities.Post) PostResponse {
+	response := PostResponse{
+		ID:        post.ID.String(),
+		Content:   post.Content,
+		Category:  string(post.Category),
+		Tags:      post.Tags,
+		Username:  post.User.Username,
+		Upvotes:   post.Upvotes,
+		Downvotes: post.Downvotes,
+		CreatedAt: post.CreatedAt,
+		IsFlagged: post.IsFlagged,
+		EditedAt:  post.EditedAt,
+		Images:    s.newImageResponses(post.Images),
+
+		LocationPrecision: string(post.LocationPrecision),
+		ExpiresAt:         post.ExpiresAt,
+	}
+	if post.IsAnonymous {
+		response.Username = nil
+		response.Pseudonym = pseudonymName(post.Pseudonym)
+	}
+	return response
+}
+
+// pseudonymName is how an anonymous participant is shown on a post
+func pseudonymName(pseudonym int) string {
+	return fmt.Sprintf("Neighbour #%d", pseudonym)
+}
+
+const (
+	// DefaultFeedLimit is the page size used when the client does not ask for one
+	DefaultFeedLimit = 20
+	// MaxFeedLimit caps the page size of the nearby feed
+	MaxFeedLimit = 100
+
+	// DefaultFeedRadius is the neighbourhood size in metres when none is given
+	DefaultFeedRadius = 5000
+	// MinFeedRadius and MaxFeedRadius bound the radius a client may request
+	MinFeedRadius = 100
+	MaxFeedRadius = 50000
+)
+
+// FeedQuery represents the parameters for reading the nearby feed
+type FeedQuery struct {
+	Latitude  float64
+	Longitude float64
+	// RadiusMeters is clamped to [MinFeedRadius, MaxFeedRadius]; zero means default
+	RadiusMeters float64
+	// Sort is one of new (default), top or hot
+	Sort string
+	// Window limits the top sort to recent posts: day, week (default), month or all
+	Window string
+	// Category and Tag restrict the feed when set
+	Category string
+	Tag      string
+	Limit    int
+	Cursor   string
+	// UserID is the caller, whose votes are reported as my_vote
+	UserID uuid.UUID
+}
+
+// FeedResponse represents a page of the nearby feed
+type FeedResponse struct {
+	Posts      []PostResponse `json:"posts"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
 
 // CreatePost creates a new post
 func (s *service) CreatePost(req CreatePostRequest, userID uuid.UUID) (*PostResponse, error) {
-	// Create the post
-	post, err := s.model.CreatePost(userID, req.Content, req.Latitude, req.Longitude)
+	category := enums.Category(req.Category)
+	if !category.IsValid() {
+		return nil, entities.ErrInvalidCategory
+	}
+
+	language := req.Language
+	if language == "" {
+		language = s.config.SearchLanguage
+	}
+	if !isSearchLanguage(language) {
+		return nil, entities.ErrInvalidLanguage
+	}
+
+	expiresAt, err := postExpiry(category, req.ExpiresIn, time.Now())
 	if err != nil {
 		return nil, err
 	}
 
+	// Coordinates are fuzzed to the chosen precision before they are stored
+	precision := enums.LocationPrecision(req.LocationPrecision)
+	if precision == "" {
+		precision = DefaultLocationPrecision
+	}
+	precision = precision.Coarser(s.config.MinLocationPrecision)
+	latitude, longitude := fuzzLocation(req.Latitude, req.Longitude, precision)
+
+	// Store the images first so a failed upload never leaves a half-made post
+	images, err := s.storePostImages(req.Images)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the post
+	post := &entities.Post{
+		UserID:    userID,
+		Content:   req.Content,
+		Category:  category,
+		Tags:      extractHashtags(req.Content),
+		Language:  language,
+		Latitude:  latitude,
+		Longitude: longitude,
+		Images:    images,
+
+		LocationPrecision: precision,
+		IsAnonymous:       req.Anonymous,
+		ExpiresAt:         expiresAt,
+	}
+	if err := s.model.CreatePost(post); err != nil {
+		s.deletePostImages(images)
+		return nil, err
+	}
+
 	// Get the user
 	user, err := s.model.GetUserByID(userID)
 	if err != nil {
@@ -39,41 +267,129 @@ func (s *service) CreatePost(req CreatePostRequest, userID uuid.UUID) (*PostResp
 	}
 
 	// Return the response
-	return &PostResponse{
-		ID:        post.ID.String(),
-		Content:   post.Content,
-		Username:  user.Username,
-		Upvotes:   post.Upvotes,
-		Downvotes: post.Downvotes,
-		CreatedAt: post.CreatedAt,
-	}, nil
+	post.User = *user
+	response := s.newPostResponse(post)
+	response.MyVote = MyVoteNone
+	return &response, nil
 }
 
-// GetNearbyPosts retrieves posts within a specified radius of a location
-func (s *service) GetNearbyPosts(latitude, longitude float64) ([]PostResponse, error) {
-	// Get posts within 5km radius
-	posts, err := s.model.GetNearbyPosts(latitude, longitude, 5000)
+// GetNearbyPosts retrieves a page of posts within a specified radius of a location
+func (s *service) GetNearbyPosts(q FeedQuery) (*FeedResponse, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultFeedLimit
+	}
+	if limit > MaxFeedLimit {
+		limit = MaxFeedLimit
+	}
+
+	radius := q.RadiusMeters
+	if radius == 0 {
+		radius = DefaultFeedRadius
+	}
+	radius = math.Max(MinFeedRadius, math.Min(MaxFeedRadius, radius))
+
+	filter := models.NearbyPostsFilter{
+		Latitude:     q.Latitude,
+		Longitude:    q.Longitude,
+		RadiusMeters: radius,
+		Sort:         models.SortNew,
+		// Fetch one extra row to know whether another page exists
+		Limit: limit + 1,
+	}
+
+	switch models.FeedSort(q.Sort) {
+	case "", models.SortNew:
+	case models.SortHot:
+		filter.Sort = models.SortHot
+	case models.SortTop:
+		filter.Sort = models.SortTop
+		since, err := topWindowStart(q.Window)
+		if err != nil {
+			return nil, err
+		}
+		filter.Since = since
+	default:
+		return nil, entities.ErrInvalidSort
+	}
+
+	if q.Category != "" {
+		filter.Category = enums.Category(q.Category)
+		if !filter.Category.IsValid() {
+			return nil, entities.ErrInvalidCategory
+		}
+	}
+
+	if q.Tag != "" {
+		tag, err := normalizeTag(q.Tag)
+		if err != nil {
+			return nil, err
+		}
+		filter.Tag = tag
+	}
+
+	if q.Cursor != "" {
+		after, err := decodePostCursor(filter.Sort, q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	// Get posts within the radius
+	posts, err := s.model.GetNearbyPosts(filter)
 	if err != nil {
 		return nil, err
 	}
 
+	feed := &FeedResponse{}
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		feed.NextCursor = encodePostCursor(filter.Sort, models.PostCursor{
+			Score:     last.Score,
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
 	// Convert to response format
-	response := make([]PostResponse, len(posts))
-	for i, post := range posts {
-		response[i] = PostResponse{
-			ID:        post.ID.String(),
-			Content:   post.Content,
-			Username:  post.User.Username,
-			Upvotes:   post.Upvotes,
-			Downvotes: post.Downvotes,
-			CreatedAt: post.CreatedAt,
-		}
+	feed.Posts = make([]PostResponse, len(posts))
+	for i := range posts {
+		distance := approximateDistance(&posts[i])
+		feed.Posts[i] = s.newPostResponse(&posts[i])
+		feed.Posts[i].DistanceMeters = &distance
 	}
 
-	return response, nil
+	if err := s.setMyVotes(q.UserID, posts, feed.Posts); err != nil {
+		return nil, err
+	}
+
+	return feed, nil
 }
 
-// GetPostByID retrieves a post by ID
+// topWindowStart converts a top-sort window name into the earliest creation time it includes
+func topWindowStart(window string) (*time.Time, error) {
+	var span time.Duration
+	switch window {
+	case "day":
+		span = 24 * time.Hour
+	case "", "week":
+		span = 7 * 24 * time.Hour
+	case "month":
+		span = 30 * 24 * time.Hour
+	case "all":
+		return nil, nil
+	default:
+		return nil, entities.ErrInvalidWindow
+	}
+
+	since := time.Now().Add(-span)
+	return &since, nil
+}
+
+// GetPostByID retrieves a post by ID for moderators, including a removed post
+// along with who removed it
 func (s *service) GetPostByID(id uuid.UUID) (*PostResponse, error) {
 	// Get the post
 	post, err := s.model.GetPostByID(id)
@@ -82,30 +398,106 @@ func (s *service) GetPostByID(id uuid.UUID) (*PostResponse, error) {
 	}
 
 	// Return the response
-	return &PostResponse{
-		ID:        post.ID.String(),
-		Content:   post.Content,
-		Username:  post.User.Username,
-		Upvotes:   post.Upvotes,
-		Downvotes: post.Downvotes,
-		CreatedAt: post.CreatedAt,
-		IsFlagged: post.IsFlagged,
-	}, nil
+	response := s.newPostResponse(post)
+	response.Username = post.User.Username
+	response.AuthorID = post.UserID.String()
+	response.Deletion = newDeletionResponse(post.DeletedAt, post.DeletedBy, post.DeletionReason)
+	return &response, nil
 }
 
-// DeletePost deletes a post
-func (s *service) DeletePost(id uuid.UUID) error {
-	return s.model.DeletePost(id)
+// EditPost replaces the content of a post. Only the author may edit, and only
+// within the configured edit window. The previous content is kept as a revision.
+func (s *service) EditPost(req EditPostRequest, postID, userID uuid.UUID) (*PostResponse, error) {
+	post, err := s.livePost(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.UserID != userID {
+		return nil, entities.ErrNotAuthor
+	}
+
+	if time.Since(post.CreatedAt) > s.config.PostEditWindow {
+		return nil, entities.ErrEditWindowClosed
+	}
+
+	if err := s.model.EditPost(post, userID, req.Content, extractHashtags(req.Content)); err != nil {
+		return nil, err
+	}
+
+	response := []PostResponse{s.newPostResponse(post)}
+	if err := s.setMyVotes(userID, []entities.Post{*post}, response); err != nil {
+		return nil, err
+	}
+	return &response[0], nil
+}
+
+// GetPostRevisions retrieves the earlier versions of a post, oldest first
+func (s *service) GetPostRevisions(postID uuid.UUID) ([]PostRevisionResponse, error) {
+	// Make sure the post exists so an unknown ID is a 404 rather than an empty list
+	if _, err := s.model.GetPostByID(postID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.model.GetPostRevisions(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]PostRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		response[i] = PostRevisionResponse{
+			ID:       revision.ID.String(),
+			Content:  revision.Content,
+			EditedBy: revision.EditedBy.String(),
+			EditedAt: revision.CreatedAt,
+		}
+	}
+
+	return response, nil
+}
+
+// DeleteOwnPost removes a post on behalf of its author
+func (s *service) DeleteOwnPost(req DeleteRequest, postID, userID uuid.UUID) error {
+	post, err := s.livePost(postID)
+	if err != nil {
+		return err
+	}
+
+	if post.UserID != userID {
+		return entities.ErrNotAuthor
+	}
+
+	return s.model.DeletePost(postID, userID, req.Reason)
+}
+
+// DeletePost removes a post on behalf of a moderator
+func (s *service) DeletePost(req DeleteRequest, postID, moderatorID uuid.UUID) error {
+	return s.model.DeletePost(postID, moderatorID, req.Reason)
 }
 
 // UpvotePost upvotes a post
 func (s *service) UpvotePost(postID, userID uuid.UUID) error {
-	return s.model.VoteOnPost(userID, postID, "upvote")
+	if _, err := s.livePost(postID); err != nil {
+		return err
+	}
+	return s.model.VoteOnPost(userID, postID, enums.VoteUp)
 }
 
 // DownvotePost downvotes a post
 func (s *service) DownvotePost(postID, userID uuid.UUID) error {
-	return s.model.VoteOnPost(userID, postID, "downvote")
+	if _, err := s.livePost(postID); err != nil {
+		return err
+	}
+	return s.model.VoteOnPost(userID, postID, enums.VoteDown)
+}
+
+// RetractPostVote removes the user's vote on a post
+func (s *service) RetractPostVote(postID, userID uuid.UUID) error {
+	if _, err := s.livePost(postID); err != nil {
+		return err
+	}
+	return s.model.RetractPostVote(userID, postID)
 }
 
 // GetFlaggedPosts retrieves all flagged posts
@@ -118,16 +510,11 @@ func (s *service) GetFlaggedPosts() ([]PostResponse, error) {
 
 	// Convert to response format
 	response := make([]PostResponse, len(posts))
-	for i, post := range posts {
-		response[i] = PostResponse{
-			ID:        post.ID.String(),
-			Content:   post.Content,
-			Username:  post.User.Username,
-			Upvotes:   post.Upvotes,
-			Downvotes: post.Downvotes,
-			CreatedAt: post.CreatedAt,
-			IsFlagged: post.IsFlagged,
-		}
+	for i := range posts {
+		response[i] = s.newPostResponse(&posts[i])
+		response[i].Username = posts[i].User.Username
+		response[i].AuthorID = posts[i].UserID.String()
+		response[i].Deletion = newDeletionResponse(posts[i].DeletedAt, posts[i].DeletedBy, posts[i].DeletionReason)
 	}
 
 	return response, nil
@@ -11,6 +11,10 @@ type ReportPostRequest struct {
 
 // ReportPost reports a post
 func (s *service) ReportPost(req ReportPostRequest, postID, userID uuid.UUID) error {
+	if _, err := s.livePost(postID); err != nil {
+		return err
+	}
+
 	// Create the report
 	_, err := s.model.CreateReport(postID, userID, req.Reason)
 	return err
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/models"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultSearchLimit is the page size when the client does not ask for one
+	DefaultSearchLimit = 20
+	// MaxSearchLimit caps the page size a client may request
+	MaxSearchLimit = 50
+	// MaxSearchQueryLength caps the length of a search query in characters
+	MaxSearchQueryLength = 200
+)
+
+// SearchLanguages are the Postgres text search configurations posts can be
+// stemmed with. simple does no stemming and suits mixed-language content.
+var SearchLanguages = []string{"simple", "english", "french", "german", "spanish", "italian", "portuguese", "dutch"}
+
+// isSearchLanguage reports whether language is one of SearchLanguages
+func isSearchLanguage(language string) bool {
+	for _, l := range SearchLanguages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchQuery represents the parameters of a full-text search near a location
+type SearchQuery struct {
+	Query     string
+	Latitude  float64
+	Longitude float64
+	// RadiusMeters is clamped to [MinFeedRadius, MaxFeedRadius]; zero means default
+	RadiusMeters float64
+	// Language restricts the search to posts written in it; empty searches
+	// posts in every language, each with the query stemmed in its language
+	Language string
+	Limit    int
+	// Cursor is the next_cursor of the previous page
+	Cursor string
+	// UserID is the caller, whose votes are reported as my_vote
+	UserID uuid.UUID
+}
+
+// SearchResultResponse represents a post matching a search
+type SearchResultResponse struct {
+	PostResponse
+	// Snippet is HTML-escaped text around the match, with matches wrapped in <mark>
+	Snippet string `json:"snippet"`
+	// MatchedCommentID is set when the snippet comes from a comment on the post
+	MatchedCommentID *string `json:"matched_comment_id,omitempty"`
+}
+
+// SearchResponse represents a page of search results, best match first
+type SearchResponse struct {
+	Results    []SearchResultResponse `json:"results"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// SearchPosts finds posts near a location whose content or comments match
+// the query, ranked by a blend of text relevance, distance and recency
+func (s *service) SearchPosts(q SearchQuery) (*SearchResponse, error) {
+	query := strings.TrimSpace(q.Query)
+	if query == "" || len([]rune(query)) > MaxSearchQueryLength {
+		return nil, entities.ErrInvalidSearchQuery
+	}
+
+	languages := SearchLanguages
+	if q.Language != "" {
+		if !isSearchLanguage(q.Language) {
+			return nil, entities.ErrInvalidLanguage
+		}
+		languages = []string{q.Language}
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	radius := q.RadiusMeters
+	if radius == 0 {
+		radius = DefaultFeedRadius
+	}
+	radius = math.Max(MinFeedRadius, math.Min(MaxFeedRadius, radius))
+
+	filter := models.PostSearchFilter{
+		Query:        query,
+		Languages:    languages,
+		Latitude:     q.Latitude,
+		Longitude:    q.Longitude,
+		RadiusMeters: radius,
+		RankedAt:     time.Now(),
+		// Fetch one extra row to know whether another page exists
+		Limit: limit + 1,
+	}
+
+	// Later pages rank as of the first, so the cursor stays meaningful
+	if q.Cursor != "" {
+		rankedAt, after, err := decodeSearchCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.RankedAt, filter.After = rankedAt, after
+	}
+
+	posts, err := s.model.SearchPosts(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := &SearchResponse{}
+	if len(posts) > limit {
+		posts = posts[:limit]
+		last := posts[len(posts)-1]
+		results.NextCursor = encodeSearchCursor(filter.RankedAt, models.PostCursor{
+			Score:     last.Score,
+			CreatedAt: last.CreatedAt,
+			ID:        last.ID,
+		})
+	}
+
+	responses := make([]PostResponse, len(posts))
+	for i := range posts {
+		distance := approximateDistance(&posts[i])
+		responses[i] = s.newPostResponse(&posts[i])
+		responses[i].DistanceMeters = &distance
+	}
+
+	if err := s.setMyVotes(q.UserID, posts, responses); err != nil {
+		return nil, err
+	}
+
+	results.Results = make([]SearchResultResponse, len(posts))
+	for i := range posts {
+		results.Results[i] = SearchResultResponse{
+			PostResponse: responses[i],
+			Snippet:      posts[i].Snippet,
+		}
+		if posts[i].MatchedCommentID != nil {
+			commentID := posts[i].MatchedCommentID.String()
+			results.Results[i].MatchedCommentID = &commentID
+		}
+	}
+
+	return results, nil
+}
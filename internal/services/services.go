@@ -1,7 +1,10 @@
 package services
 
 import (
+	"hyperlocal/internal/keys"
 	"hyperlocal/internal/models"
+	"hyperlocal/internal/storage"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,42 +12,71 @@ import (
 // Service represents the service layer having
 // all the services from all service packages
 type service struct {
-	model models.Model
+	model    models.Model
+	keys     *keys.KeyRing
+	blobs    storage.BlobStore
+	config   Config
+	throttle loginThrottle
 }
 
 // New creates a new instance of Service
-func New(model *models.Model) Service {
+func New(model *models.Model, keyRing *keys.KeyRing, blobs storage.BlobStore, config Config) Service {
 	return &service{
-		model: *model,
+		model:    *model,
+		keys:     keyRing,
+		blobs:    blobs,
+		config:   config,
+		throttle: loginThrottle{store: model, now: time.Now},
 	}
 }
 
 // Service defines the interface for the service layer
 type Service interface {
 	// Auth services
-	Register(req RegisterRequest) (*TokenResponse, error)
-	Login(req LoginRequest) (*TokenResponse, error)
-	RefreshToken(req RefreshTokenRequest) (*TokenResponse, error)
+	Register(req RegisterRequest, client ClientInfo) (*TokenResponse, error)
+	Login(req LoginRequest, client ClientInfo) (*TokenResponse, error)
+	RefreshToken(req RefreshTokenRequest, client ClientInfo) (*TokenResponse, error)
 	ValidateToken(tokenString string) (*JWTClaims, error)
+	Logout(req LogoutRequest) error
+	GetSessions(userID uuid.UUID, currentSessionID string) ([]SessionResponse, error)
+	RevokeSession(userID, sessionID uuid.UUID) error
+	RevokeAllSessions(userID uuid.UUID) error
+	JWKS() keys.JWKSet
 
 	// Post services
 	CreatePost(req CreatePostRequest, userID uuid.UUID) (*PostResponse, error)
-	GetNearbyPosts(latitude, longitude float64) ([]PostResponse, error)
+	GetNearbyPosts(q FeedQuery) (*FeedResponse, error)
 	GetPostByID(id uuid.UUID) (*PostResponse, error)
-	DeletePost(id uuid.UUID) error
+	EditPost(req EditPostRequest, postID, userID uuid.UUID) (*PostResponse, error)
+	GetPostRevisions(postID uuid.UUID) ([]PostRevisionResponse, error)
+	DeleteOwnPost(req DeleteRequest, postID, userID uuid.UUID) error
+	GetCategories() []CategoryResponse
+	SearchPosts(q SearchQuery) (*SearchResponse, error)
+	ImageVisible(key string) (bool, error)
 
 	// Vote services
 	UpvotePost(postID, userID uuid.UUID) error
 	DownvotePost(postID, userID uuid.UUID) error
+	RetractPostVote(postID, userID uuid.UUID) error
 
 	// Comment services
 	CreateComment(req CreateCommentRequest, postID, userID uuid.UUID) (*CommentResponse, error)
-	GetCommentsByPostID(postID uuid.UUID) ([]CommentResponse, error)
+	GetCommentsByPostID(postID uuid.UUID, order string) ([]CommentResponse, error)
+	DeleteOwnComment(req DeleteRequest, postID, commentID, userID uuid.UUID) error
+	UpvoteComment(postID, commentID, userID uuid.UUID) error
+	DownvoteComment(postID, commentID, userID uuid.UUID) error
 
 	// Report services
 	ReportPost(req ReportPostRequest, postID, userID uuid.UUID) error
 
 	// Admin services
 	GetFlaggedPosts() ([]PostResponse, error)
+	DeletePost(req DeleteRequest, postID, moderatorID uuid.UUID) error
+	GetCommentsForModeration(postID uuid.UUID) ([]CommentResponse, error)
 	BanUser(userID uuid.UUID) error
+	SetUserRole(actorID, userID uuid.UUID, role string) error
+	ClearLoginLockout(userID uuid.UUID) error
+	ReconcileVoteCounts() (int64, error)
+	PurgeExpiredPosts() (int64, error)
+	CoarsenPostLocations() (int64, error)
 }
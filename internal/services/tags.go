@@ -0,0 +1,72 @@
+package services
+
+import (
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"regexp"
+	"strings"
+)
+
+// MaxPostTags is how many hashtags are kept per post
+const MaxPostTags = 10
+
+var (
+	// hashtagPattern matches #word, where word is up to 50 letters, digits or underscores
+	hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]{1,50})`)
+	// tagPattern matches a tag on its own, as given to the feed filter
+	tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_]{1,50}$`)
+)
+
+// extractHashtags returns the distinct hashtags in content, lowercased, in
+// order of first appearance
+func extractHashtags(content string) entities.Tags {
+	tags := entities.Tags{}
+	seen := make(map[string]bool)
+	for _, match := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(match[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+
+		tags = append(tags, tag)
+		if len(tags) == MaxPostTags {
+			break
+		}
+	}
+	return tags
+}
+
+// normalizeTag turns a tag filter such as "#Garage_Sale" into its stored form
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if !tagPattern.MatchString(tag) {
+		return "", entities.ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// CategoryResponse represents a post category
+type CategoryResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// categoryNames are the display names of the curated categories
+var categoryNames = map[enums.Category]string{
+	enums.CategoryGeneral:        "General",
+	enums.CategoryLostAndFound:   "Lost & found",
+	enums.CategorySafetyAlert:    "Safety alert",
+	enums.CategoryEvent:          "Event",
+	enums.CategoryRecommendation: "Recommendation",
+	enums.CategoryForSale:        "For sale",
+}
+
+// GetCategories lists the categories a post can be filed under
+func (s *service) GetCategories() []CategoryResponse {
+	categories := make([]CategoryResponse, len(enums.Categories))
+	for i, category := range enums.Categories {
+		categories[i] = CategoryResponse{ID: string(category), Name: categoryNames[category]}
+	}
+	return categories
+}
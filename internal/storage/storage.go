@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrInvalidKey is returned for keys that are empty or escape the store
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore stores uploaded files under slash-separated keys such as
+// "images/<id>.jpg" and knows the public URL each one is served from.
+// LocalStore keeps blobs on disk; an S3-compatible store only has to
+// implement the same three methods.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
@@ -2,10 +2,16 @@ package rest
 
 import (
 	"context"
+	"hyperlocal/internal/entities"
+	"hyperlocal/internal/entities/enums"
+	"hyperlocal/internal/handlers/response"
+	"hyperlocal/internal/ratelimit"
 	"hyperlocal/internal/services"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,13 +24,13 @@ func AuthMiddleware(service services.Service) func(http.Handler) http.Handler {
 			// Get the Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header is required", http.StatusUnauthorized)
+				response.Error(w, r, entities.ErrAuthRequired)
 				return
 			}
 
 			// Check if the header has the Bearer prefix
 			if !strings.HasPrefix(authHeader, "Bearer ") {
-				http.Error(w, "Invalid authorization format", http.StatusUnauthorized)
+				response.Error(w, r, entities.ErrInvalidToken)
 				return
 			}
 
@@ -34,20 +40,21 @@ func AuthMiddleware(service services.Service) func(http.Handler) http.Handler {
 			// Validate the token
 			claims, err := service.ValidateToken(tokenString)
 			if err != nil {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				response.Error(w, r, entities.ErrInvalidToken)
 				return
 			}
 
 			// Parse the user ID
 			userID, err := uuid.Parse(claims.UserID)
 			if err != nil {
-				http.Error(w, "Invalid user ID in token", http.StatusUnauthorized)
+				response.Error(w, r, entities.ErrInvalidToken)
 				return
 			}
 
 			// Set the user ID in the context
 			ctx := context.WithValue(r.Context(), "userID", userID)
 			ctx = context.WithValue(ctx, "role", claims.Role)
+			ctx = context.WithValue(ctx, "sessionID", claims.SessionID)
 
 			// Call the next handler with the updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -60,125 +67,187 @@ func AuthMiddlewareFunc(service services.Service) func(http.Handler) http.Handle
 	return AuthMiddleware(service)
 }
 
+// RequireRole ensures the user has one of the given roles
+func RequireRole(roles ...enums.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Get the role from the context
+			role, ok := r.Context().Value("role").(string)
+			if !ok {
+				response.Error(w, r, entities.ErrAuthRequired)
+				return
+			}
+
+			// Check if the user has one of the allowed roles
+			for _, allowed := range roles {
+				if enums.Role(role) == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			response.Error(w, r, entities.ErrInsufficientRole)
+		})
+	}
+}
+
 // AdminMiddleware ensures the user has admin role
 func AdminMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get the role from the context
-		role := r.Context().Value("role")
-		if role == nil {
-			http.Error(w, "Authentication required", http.StatusUnauthorized)
-			return
-		}
+	return RequireRole(enums.RoleAdmin)(next)
+}
 
-		// Check if the user is an admin
-		if role != "admin" {
-			http.Error(w, "Admin access required", http.StatusForbidden)
-			return
-		}
+// ModeratorMiddleware ensures the user is a moderator or an admin
+func ModeratorMiddleware(next http.Handler) http.Handler {
+	return RequireRole(enums.RoleModerator, enums.RoleAdmin)(next)
+}
+
+// VisibleMediaMiddleware only lets through blobs whose post is neither
+// deleted nor expired. Those blobs are kept until the post is purged, so
+// moderators, who may still review the post, are let through with a valid
+// bearer token. It expects the media prefix to be stripped already.
+func VisibleMediaMiddleware(service services.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			visible, err := service.ImageVisible(strings.TrimPrefix(r.URL.Path, "/"))
+			if err != nil {
+				response.Error(w, r, err)
+				return
+			}
+
+			if !visible && !isModeratorRequest(service, r) {
+				http.NotFound(w, r)
+				return
+			}
 
-		next.ServeHTTP(w, r)
-	})
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-// RateLimiterMiddleware implements a simple in-memory rate limiter
-func RateLimiterMiddleware(next http.Handler) http.Handler {
-	// Simple in-memory store for rate limiting
-	// In production, use Redis or a similar distributed store
-	type rateLimitEntry struct {
-		count    int
-		lastSeen int64
+// isModeratorRequest reports whether r carries a valid token of a moderator or admin
+func isModeratorRequest(service services.Service, r *http.Request) bool {
+	tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	claims, err := service.ValidateToken(tokenString)
+	if err != nil {
+		return false
 	}
-	
-	// Store user IDs and their request counts
-	// In a real app, this would be a Redis cache or similar
-	rateLimits := make(map[string]rateLimitEntry)
-	var mu sync.Mutex
-	
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get the user ID from the context
-		userIDValue := r.Context().Value("userID")
-		if userIDValue == nil {
+
+	role := enums.Role(claims.Role)
+	return role == enums.RoleModerator || role == enums.RoleAdmin
+}
+
+// RateLimitMiddleware applies a token bucket policy, keyed by the authenticated
+// user or by client IP for anonymous requests, and reports the bucket state in
+// the RateLimit-* headers
+func RateLimitMiddleware(store ratelimit.Store, policy ratelimit.Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := policy.Name + ":ip:" + clientIP(r)
+			if userID, ok := r.Context().Value("userID").(uuid.UUID); ok {
+				key = policy.Name + ":user:" + userID.String()
+			}
+
+			result, err := store.Take(r.Context(), key, policy, time.Now())
+			if err != nil {
+				// Fail open, an unavailable store should not take the API down
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", policy.String())
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				response.Error(w, r, entities.ErrRateLimitExceeded)
+				return
+			}
+
 			next.ServeHTTP(w, r)
-			return
-		}
-		
-		userID, ok := userIDValue.(uuid.UUID)
-		if !ok {
+		})
+	}
+}
+
+// RealIPMiddleware replaces r.RemoteAddr with the client address reported by
+// a trusted reverse proxy. X-Forwarded-For and X-Real-IP are set by clients
+// as much as by proxies, so they are only read when the connection itself
+// comes from a trusted network; otherwise the socket address is kept. Rate
+// limits, login throttling and session records all rely on this.
+func RealIPMiddleware(trusted []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := forwardedClientIP(r, trusted); ip != "" {
+				r.RemoteAddr = ip
+			}
 			next.ServeHTTP(w, r)
-			return
-		}
-		
-		userIDStr := userID.String()
-		
-		mu.Lock()
-		defer mu.Unlock()
-		
-		now := time.Now().Unix()
-		entry, exists := rateLimits[userIDStr]
-		
-		// Reset count if it's been more than a minute
-		if exists && now - entry.lastSeen > 60 {
-			entry.count = 0
-		}
-		
-		// Update the entry
-		entry.count++
-		entry.lastSeen = now
-		rateLimits[userIDStr] = entry
-		
-		// Check if the user has exceeded the rate limit
-		if entry.count > 10 { // 10 requests per minute
-			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
-			return
-		}
-		
-		next.ServeHTTP(w, r)
-	})
+		})
+	}
 }
-		// Get the user ID from the context
-		userIDValue := r.Context().Value("userID")
-		if !exists {
-			c.Next()
-			return
+
+// forwardedClientIP returns the client address reported by trusted proxies,
+// or "" if the request did not come through one
+func forwardedClientIP(r *http.Request, trusted []*net.IPNet) string {
+	if !isTrustedProxy(net.ParseIP(clientIP(r)), trusted) {
+		return ""
+	}
+
+	// Each proxy appends the address it received the request from, so walk
+	// back from the nearest hop; the first address that is not one of our
+	// proxies is the client, anything before it may be forged
+	if forwarded := strings.Join(r.Header.Values("X-Forwarded-For"), ","); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		var client net.IP
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip
+			if !isTrustedProxy(ip, trusted) {
+				break
+			}
 		}
-		
-		userID, ok := userIDValue.(uuid.UUID)
-		if !ok {
-			c.Next()
-			return
+		if client != nil {
+			return client.String()
 		}
-		
-		userIDStr := userID.String()
-		
-		// Check if this is a POST request to /posts
-		if c.Request.Method == "POST" && strings.HasPrefix(c.Request.URL.Path, "/posts") && !strings.Contains(c.Request.URL.Path, "/comments") {
-			// Get the current timestamp
-			now := c.Request.Context().Value("requestTime").(int64)
-			
-			// Get the user's rate limit entry
-			entry, exists := rateLimits[userIDStr]
-			
-			// If the entry doesn't exist or is older than an hour, create a new one
-			if !exists || now-entry.lastSeen > 3600 {
-				rateLimits[userIDStr] = rateLimitEntry{
-					count:    1,
-					lastSeen: now,
-				}
-			} else {
-				// If the user has already made 3 requests in the last hour, reject the request
-				if entry.count >= 3 {
-					c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded (3 posts per hour)"})
-					c.Abort()
-					return
-				}
-				
-				// Otherwise, increment the count
-				entry.count++
-				entry.lastSeen = now
-				rateLimits[userIDStr] = entry
-			}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// isTrustedProxy reports whether ip belongs to one of the trusted networks
+func isTrustedProxy(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
 		}
-		
-		c.Next()
 	}
-}
\ No newline at end of file
+	return false
+}
+
+// clientIP returns the request's remote address without the port, as set by
+// RealIPMiddleware
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// ceilSeconds rounds a duration up to whole seconds for HTTP headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
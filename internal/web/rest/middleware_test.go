@@ -0,0 +1,176 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"hyperlocal/internal/ratelimit"
+
+	"github.com/google/uuid"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		wantRemoteIP string
+	}{
+		{"direct client ignores headers", "203.0.113.7:5000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"forged hops before the proxy are ignored", "10.0.0.2:5000", "1.1.1.1, 198.51.100.1", "", "198.51.100.1"},
+		{"chained trusted proxies", "10.0.0.2:5000", "198.51.100.1, 192.168.1.1, 10.0.0.3", "", "198.51.100.1"},
+		{"real ip header from trusted proxy", "192.168.1.1:5000", "", "198.51.100.9", "198.51.100.9"},
+		{"no headers", "10.0.0.2:5000", "", "", "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RealIPMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.wantRemoteIP {
+				t.Errorf("client IP = %q, want %q", got, tt.wantRemoteIP)
+			}
+		})
+	}
+}
+
+// fakeStore answers every Take with a fixed result and records what was asked
+type fakeStore struct {
+	result   ratelimit.Result
+	err      error
+	keys     []string
+	policies []ratelimit.Policy
+}
+
+func (s *fakeStore) Take(ctx context.Context, key string, policy ratelimit.Policy, now time.Time) (ratelimit.Result, error) {
+	s.keys = append(s.keys, key)
+	s.policies = append(s.policies, policy)
+	return s.result, s.err
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	policy := ratelimit.Policy{Name: "posts", Limit: 3, Period: time.Hour}
+	userID := uuid.New()
+
+	tests := []struct {
+		name        string
+		store       *fakeStore
+		user        bool
+		wantKey     string
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name:       "anonymous requests are keyed by client IP",
+			store:      &fakeStore{result: ratelimit.Result{Allowed: true, Remaining: 2, ResetAfter: 20 * time.Minute}},
+			wantKey:    "posts:ip:203.0.113.7",
+			wantStatus: http.StatusOK,
+			wantHeaders: map[string]string{
+				"RateLimit-Policy":    "3;w=3600",
+				"RateLimit-Limit":     "3",
+				"RateLimit-Remaining": "2",
+				"RateLimit-Reset":     "1200",
+			},
+		},
+		{
+			name:       "authenticated requests are keyed by user",
+			store:      &fakeStore{result: ratelimit.Result{Allowed: true, Remaining: 1}},
+			user:       true,
+			wantKey:    "posts:user:" + userID.String(),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "an empty bucket is refused with Retry-After",
+			store:      &fakeStore{result: ratelimit.Result{RetryAfter: 1500 * time.Millisecond, ResetAfter: time.Hour}},
+			user:       true,
+			wantKey:    "posts:user:" + userID.String(),
+			wantStatus: http.StatusTooManyRequests,
+			wantHeaders: map[string]string{
+				"Retry-After":         "2",
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "3600",
+			},
+		},
+		{
+			name:       "an unavailable store fails open",
+			store:      &fakeStore{err: errors.New("store down")},
+			wantKey:    "posts:ip:203.0.113.7",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RateLimitMiddleware(tt.store, policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/posts", nil)
+			r.RemoteAddr = "203.0.113.7:5000"
+			if tt.user {
+				r = r.WithContext(context.WithValue(r.Context(), "userID", userID))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if len(tt.store.keys) != 1 || tt.store.keys[0] != tt.wantKey {
+				t.Errorf("bucket keys = %v, want [%s]", tt.store.keys, tt.wantKey)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			for header, want := range tt.wantHeaders {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterUsesConfiguredPolicies(t *testing.T) {
+	store := &fakeStore{result: ratelimit.Result{Allowed: true}}
+	override := ratelimit.Policy{Name: ratelimit.GroupVotes, Limit: 5, Period: time.Minute}
+	limit := rateLimiter(Config{
+		RateLimitStore:    store,
+		RateLimitPolicies: map[string]ratelimit.Policy{ratelimit.GroupVotes: override},
+	})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, group := range []string{ratelimit.GroupVotes, ratelimit.GroupPosts} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		limit(group)(next).ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if len(store.policies) != 2 {
+		t.Fatalf("store asked %d times, want 2", len(store.policies))
+	}
+	if store.policies[0] != override {
+		t.Errorf("votes policy = %+v, want the configured %+v", store.policies[0], override)
+	}
+	if want := ratelimit.DefaultPolicies()[ratelimit.GroupPosts]; store.policies[1] != want {
+		t.Errorf("posts policy = %+v, want the default %+v", store.policies[1], want)
+	}
+}
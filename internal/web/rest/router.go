@@ -2,13 +2,68 @@ package rest
 
 import (
 	"hyperlocal/internal/handlers"
+	"hyperlocal/internal/ratelimit"
+	"hyperlocal/internal/services"
+	"net/http"
 
-	"github.com/gorilla/mux"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/cors"
+	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
-// NewRouter returns a new router instance with configured routes
-func NewRouter(h *handlers.Handler) *mux.Router {
-	router := mux.NewRouter()
+// NewRouter returns the root HTTP handler with the API, Swagger and CORS configured
+func NewRouter(handler *handlers.Handler, service services.Service, cfg Config) http.Handler {
+	r := chi.NewRouter()
 
-	return router
+	// Middleware
+	r.Use(middleware.RequestID)
+	r.Use(RealIPMiddleware(cfg.TrustedProxies))
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+
+	// Swagger documentation
+	r.Get("/swagger/*", httpSwagger.Handler(
+		httpSwagger.URL("/swagger/doc.json"),
+		httpSwagger.DeepLinking(true),
+		httpSwagger.DocExpansion("none"),
+		httpSwagger.DomID("swagger-ui"),
+	))
+
+	// Token verification keys for other services
+	r.Get("/.well-known/jwks.json", handler.V1.JWKS)
+
+	// Uploaded images
+	if cfg.Media != nil {
+		r.Handle(cfg.MediaPrefix+"/*", http.StripPrefix(cfg.MediaPrefix, VisibleMediaMiddleware(service)(cfg.Media)))
+	}
+
+	// API v1 routes
+	r.Mount("/api/v1", apiV1Routes(handler, service, rateLimiter(cfg)))
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		AllowCredentials: true,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+	})
+
+	return c.Handler(r)
+}
+
+// rateLimiter returns a function building the rate limit middleware of a route group
+func rateLimiter(cfg Config) func(group string) func(http.Handler) http.Handler {
+	store := cfg.RateLimitStore
+	if store == nil {
+		store = ratelimit.NewMemoryStore()
+	}
+
+	policies := ratelimit.DefaultPolicies()
+	for group, policy := range cfg.RateLimitPolicies {
+		policies[group] = policy
+	}
+
+	return func(group string) func(http.Handler) http.Handler {
+		return RateLimitMiddleware(store, policies[group])
+	}
 }
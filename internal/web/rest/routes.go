@@ -2,59 +2,87 @@ package rest
 
 import (
 	"hyperlocal/internal/handlers"
+	"hyperlocal/internal/ratelimit"
+	"hyperlocal/internal/services"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
-// SetupRouter configures all routes for the application
-func SetupRouter(handler *handlers.Handler) http.Handler {
+// apiV1Routes builds the /api/v1 route tree. limit returns the rate limit
+// middleware of a route group.
+func apiV1Routes(handler *handlers.Handler, service services.Service, limit func(group string) func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
-	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-
-	// Swagger documentation
-	r.Get("/swagger/*", httpSwagger.Handler())
+	// Auth routes - no middleware required, except session management
+	r.Route("/auth", func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(limit(ratelimit.GroupAuth))
 
-	// API v1 routes
-	r.Route("/api/v1", func(r chi.Router) {
-		// Auth routes - no middleware required
-		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", handler.V1.Register)
 			r.Post("/login", handler.V1.Login)
 			r.Post("/refresh", handler.V1.RefreshToken)
 		})
+		r.Post("/logout", handler.V1.Logout)
 
-		// Protected routes - require authentication
 		r.Group(func(r chi.Router) {
-			r = r.With(AuthMiddlewareFunc(handler.V1.Service))
+			r.Use(AuthMiddlewareFunc(service))
 
-			// Posts
-			r.Route("/posts", func(r chi.Router) {
-				r.With(RateLimiterMiddleware).Post("/", handler.V1.CreatePost)
-				r.Get("/", handler.V1.GetNearbyPosts)
+			r.Get("/sessions", handler.V1.GetSessions)
+			r.Delete("/sessions", handler.V1.RevokeAllSessions)
+			r.Delete("/sessions/{id}", handler.V1.RevokeSession)
+		})
+	})
 
-				// Post interactions
-				r.Post("/{id}/upvote", handler.V1.UpvotePost)
-				r.Post("/{id}/downvote", handler.V1.DownvotePost)
-				r.Post("/{id}/report", handler.V1.ReportPost)
+	// Post categories - public so clients can render the picker before login
+	r.Get("/categories", handler.V1.GetCategories)
 
-				// Comments
-				r.Post("/{id}/comments", handler.V1.CreateComment)
-				r.Get("/{id}/comments", handler.V1.GetComments)
-			})
+	// Protected routes - require authentication
+	r.Group(func(r chi.Router) {
+		r.Use(AuthMiddlewareFunc(service))
 
-			// Admin routes - require admin role
-			r.Route("/admin", func(r chi.Router) {
-				r.Use(AdminMiddleware)
+		// Posts
+		r.Route("/posts", func(r chi.Router) {
+			r.With(limit(ratelimit.GroupPosts)).Post("/", handler.V1.CreatePost)
+			r.Get("/", handler.V1.GetNearbyPosts)
+			r.Get("/search", handler.V1.SearchPosts)
+			r.Patch("/{id}", handler.V1.EditPost)
+			r.Delete("/{id}", handler.V1.DeleteOwnPost)
+
+			// Post interactions
+			r.With(limit(ratelimit.GroupVotes)).Post("/{id}/upvote", handler.V1.UpvotePost)
+			r.With(limit(ratelimit.GroupVotes)).Post("/{id}/downvote", handler.V1.DownvotePost)
+			r.With(limit(ratelimit.GroupVotes)).Delete("/{id}/vote", handler.V1.RetractPostVote)
+			r.With(limit(ratelimit.GroupReports)).Post("/{id}/report", handler.V1.ReportPost)
+
+			// Comments
+			r.With(limit(ratelimit.GroupComments)).Post("/{id}/comments", handler.V1.CreateComment)
+			r.Get("/{id}/comments", handler.V1.GetComments)
+			r.Delete("/{id}/comments/{commentID}", handler.V1.DeleteComment)
+			r.With(limit(ratelimit.GroupVotes)).Post("/{id}/comments/{commentID}/upvote", handler.V1.UpvoteComment)
+			r.With(limit(ratelimit.GroupVotes)).Post("/{id}/comments/{commentID}/downvote", handler.V1.DownvoteComment)
+		})
+
+		// Admin routes - moderation requires the moderator role,
+		// user management requires the admin role
+		r.Route("/admin", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(ModeratorMiddleware)
 
 				r.Get("/flagged", handler.V1.GetFlaggedPosts)
+				r.Get("/posts/{id}", handler.V1.GetPostForModeration)
 				r.Delete("/posts/{id}", handler.V1.DeletePost)
+				r.Get("/posts/{id}/revisions", handler.V1.GetPostRevisions)
+				r.Get("/posts/{id}/comments", handler.V1.GetCommentsForModeration)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(AdminMiddleware)
+
 				r.Patch("/users/{id}/ban", handler.V1.BanUser)
+				r.Put("/users/{id}/role", handler.V1.SetUserRole)
+				r.Delete("/users/{id}/role", handler.V1.RevokeUserRole)
+				r.Delete("/users/{id}/lockout", handler.V1.ClearLoginLockout)
 			})
 		})
 	})
@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"fmt"
+	"hyperlocal/internal/env"
+	"hyperlocal/internal/handlers"
+	"hyperlocal/internal/ratelimit"
+	"hyperlocal/internal/services"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config holds the HTTP server settings
+type Config struct {
+	Addr            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	AllowedOrigins  []string
+
+	// TrustedProxies are the networks of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed; requests from
+	// anywhere else are identified by their socket address
+	TrustedProxies []*net.IPNet
+
+	// RateLimitPolicies maps route groups to their policy, see ratelimit.DefaultPolicies
+	RateLimitPolicies map[string]ratelimit.Policy
+	// RateLimitStore holds bucket state; nil uses an in-memory store
+	RateLimitStore ratelimit.Store
+
+	// Media serves uploaded blobs under MediaPrefix; nil when they are
+	// served from elsewhere, such as a CDN in front of the blob store
+	MediaPrefix string
+	Media       http.Handler
+}
+
+// ConfigFromEnv builds a Config from environment variables, falling back to
+// defaults for unset ones and failing on invalid ones
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Addr:            ":8080",
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    15 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ShutdownTimeout: 20 * time.Second,
+		AllowedOrigins:  []string{"http://localhost:3000"},
+	}
+
+	timeouts := map[string]*time.Duration{
+		"HTTP_READ_TIMEOUT":     &cfg.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT":    &cfg.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":     &cfg.IdleTimeout,
+		"HTTP_SHUTDOWN_TIMEOUT": &cfg.ShutdownTimeout,
+	}
+	for key, d := range timeouts {
+		if err := env.Duration(key, d); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if addr := os.Getenv("HTTP_ADDR"); addr != "" {
+		cfg.Addr = addr
+	} else if port := os.Getenv("PORT"); port != "" {
+		cfg.Addr = ":" + port
+	}
+
+	if os.Getenv("ENV") == "prod" {
+		cfg.AllowedOrigins = []string{"https://hyperlocal-frontend.vercel.app"}
+	}
+
+	trusted, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.TrustedProxies = trusted
+
+	policies, err := ratelimit.PoliciesFromEnv()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.RateLimitPolicies = policies
+
+	return cfg, nil
+}
+
+// NewServer creates an http.Server serving the application router
+func NewServer(cfg Config, handler *handlers.Handler, service services.Service) *http.Server {
+	return &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      NewRouter(handler, service, cfg),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
+
+// parseTrustedProxies parses a comma-separated list of CIDRs or single IPs
+func parseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}